@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Separates the segments of a configuration address
+const addressSeparator = "/"
+
+// Matches any number of configuration levels in an address
+const addressAnyDepth = "**"
+
+// Stores a configuration found by an address together with its full path
+type AddressMatch struct {
+	Module        *Module
+	Configuration *Configuration
+	Path          string
+}
+
+// Splits an address like F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected into its segments and checks the wildcards
+func ParseAddress(address string) ([]string, error) {
+	address = strings.Trim(address, addressSeparator)
+	if len(address) == 0 {
+		return nil, errors.New("the address is empty")
+	}
+	segments := strings.Split(address, addressSeparator)
+	for _, segment := range segments {
+		if len(segment) == 0 {
+			return nil, fmt.Errorf("the address %s has an empty segment", address)
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("the address %s has an invalid segment %s: %w", address, segment, err)
+		}
+	}
+	if segments[0] == addressAnyDepth {
+		return nil, fmt.Errorf("the address %s must start with a module", address)
+	}
+	return segments, nil
+}
+
+// Joins a module and an optional configuration address within it into a single address
+func JoinAddress(module string, sub string) string {
+	if len(sub) == 0 {
+		return module
+	}
+	return strings.TrimRight(module, addressSeparator) + addressSeparator + strings.TrimLeft(sub, addressSeparator)
+}
+
+func hasWildcard(segment string) bool {
+	return strings.ContainsAny(segment, "*?[")
+}
+
+// Returns every module and configuration matched by the address
+func (r *ModuleRegistry) ResolveAll(address string) ([]AddressMatch, error) {
+	segments, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []*Module
+	if hasWildcard(segments[0]) {
+		r.ForEach(func(m *Module) bool {
+			if matched, _ := path.Match(segments[0], m.Name); matched {
+				modules = append(modules, m)
+			}
+			return true
+		})
+	} else {
+		found, err := r.Find(segments[0])
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, found)
+	}
+
+	var matches []AddressMatch
+	for _, currentModule := range modules {
+		if len(segments) == 1 {
+			matches = append(matches, AddressMatch{Module: currentModule, Path: currentModule.Name})
+			continue
+		}
+		seen := map[*Configuration]bool{}
+		matchConfigurations(currentModule, currentModule.Configurations, segments[1:], currentModule.Name, seen, &matches)
+	}
+	return matches, nil
+}
+
+// Walks the configurations, appending every configuration matched by the remaining segments
+func matchConfigurations(module *Module, configs []Configuration, segments []string, prefix string, seen map[*Configuration]bool, matches *[]AddressMatch) {
+	segment, rest := segments[0], segments[1:]
+	if segment == addressAnyDepth && len(rest) > 0 {
+		// Zero levels, then one or more levels below each configuration
+		matchConfigurations(module, configs, rest, prefix, seen, matches)
+		for i := range configs {
+			currentConfig := &configs[i]
+			matchConfigurations(module, currentConfig.Configurations, segments, prefix+addressSeparator+currentConfig.Name, seen, matches)
+		}
+		return
+	}
+	for i := range configs {
+		currentConfig := &configs[i]
+		currentPath := prefix + addressSeparator + currentConfig.Name
+		if segment == addressAnyDepth {
+			// A trailing ** matches every configuration below this level
+			addMatch(module, currentConfig, currentPath, seen, matches)
+			matchConfigurations(module, currentConfig.Configurations, segments, currentPath, seen, matches)
+			continue
+		}
+		if matched, _ := path.Match(segment, currentConfig.Name); !matched {
+			continue
+		}
+		if len(rest) == 0 {
+			addMatch(module, currentConfig, currentPath, seen, matches)
+		} else {
+			matchConfigurations(module, currentConfig.Configurations, rest, currentPath, seen, matches)
+		}
+	}
+}
+
+func addMatch(module *Module, config *Configuration, configPath string, seen map[*Configuration]bool, matches *[]AddressMatch) {
+	if seen[config] {
+		return
+	}
+	seen[config] = true
+	*matches = append(*matches, AddressMatch{Module: module, Configuration: config, Path: configPath})
+}
+
+// Returns the single configuration matched by the address, missing and ambiguous addresses are errors
+func (r *ModuleRegistry) Resolve(address string) (*AddressMatch, error) {
+	matches, err := r.ResolveAll(address)
+	if err != nil {
+		return nil, err
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no configuration matches the address %s", address)
+	case 1:
+		return &matches[0], nil
+	}
+	paths := make([]string, len(matches))
+	for i, match := range matches {
+		paths[i] = match.Path
+	}
+	return nil, fmt.Errorf("the address %s is ambiguous, it matches %s", address, strings.Join(paths, ", "))
+}
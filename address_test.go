@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testAddressRegistry(t *testing.T) *ModuleRegistry {
+	t.Helper()
+	modules := Modules{
+		{
+			Name: "F-14RHV",
+			Tag:  "F-14B",
+			Configurations: []Configuration{
+				{Name: "LMFD_TomcatRIO", Configurations: []Configuration{
+					{Name: "BIT", Configurations: []Configuration{{Name: "BIT_Selected"}}},
+					{Name: "SPL", Configurations: []Configuration{{Name: "SPL_Selected"}}},
+				}},
+				{Name: "RMFD_TomcatRIO", Configurations: []Configuration{{Name: "Flir"}}},
+			},
+		},
+		{Name: "F-14PHV", Tag: "F-14B"},
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatalf("NewModuleRegistry() error = %v", err)
+	}
+	return registry
+}
+
+func TestModuleRegistry_ResolveAll(t *testing.T) {
+	registry := testAddressRegistry(t)
+	tests := []struct {
+		address string
+		want    []string
+		wantErr bool
+	}{
+		{address: "F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected", want: []string{"F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected"}},
+		{address: "F-14RHV/LMFD_TomcatRIO/*/*_Selected", want: []string{"F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected", "F-14RHV/LMFD_TomcatRIO/SPL/SPL_Selected"}},
+		{address: "F-14RHV/**/Flir", want: []string{"F-14RHV/RMFD_TomcatRIO/Flir"}},
+		{address: "F-14RHV/LMFD_TomcatRIO/**", want: []string{"F-14RHV/LMFD_TomcatRIO/BIT", "F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected", "F-14RHV/LMFD_TomcatRIO/SPL", "F-14RHV/LMFD_TomcatRIO/SPL/SPL_Selected"}},
+		{address: "F-14?HV", want: []string{"F-14PHV", "F-14RHV"}},
+		{address: "F-14RHV/LMFD_TomcatRIO/NAV", want: nil},
+		{address: "F-14RHV//BIT", wantErr: true},
+		{address: "F-14RHV/[BIT", wantErr: true},
+		{address: "**/BIT", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			matches, err := registry.ResolveAll(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ModuleRegistry.ResolveAll() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var got []string
+			for _, match := range matches {
+				got = append(got, match.Path)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ModuleRegistry.ResolveAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleRegistry_Resolve(t *testing.T) {
+	registry := testAddressRegistry(t)
+
+	match, err := registry.Resolve(JoinAddress("F-14RHV", "LMFD_TomcatRIO/SPL"))
+	if err != nil {
+		t.Fatalf("ModuleRegistry.Resolve() error = %v", err)
+	}
+	if match.Configuration.Name != "SPL" || match.Module.Name != "F-14RHV" {
+		t.Errorf("ModuleRegistry.Resolve() = %s", match.Path)
+	}
+
+	_, err = registry.Resolve("F-14RHV/LMFD_TomcatRIO/*")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("ModuleRegistry.Resolve() error = %v, want ambiguous", err)
+	}
+	_, err = registry.Resolve("F-14RHV/LMFD_TomcatRIO/NAV")
+	if err == nil || !strings.Contains(err.Error(), "no configuration") {
+		t.Errorf("ModuleRegistry.Resolve() error = %v, want missing", err)
+	}
+}
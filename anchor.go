@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Marks a position or size relative to the parent rectangle, such as "width": "50%"
+const percentSuffix = "%"
+
+// The fields that may hold a percentage of the parent rectangle
+var percentFields = map[string]bool{"left": true, "top": true, "width": true, "height": true}
+
+// The anchors that place a configuration inside its parent, by the horizontal and vertical edge they keep to
+var anchors = map[string]struct{ horizontal, vertical string }{
+	"top-left":     {"left", "top"},
+	"top":          {"center", "top"},
+	"top-right":    {"right", "top"},
+	"left":         {"left", "center"},
+	"center":       {"center", "center"},
+	"right":        {"right", "center"},
+	"bottom-left":  {"left", "bottom"},
+	"bottom":       {"center", "bottom"},
+	"bottom-right": {"right", "bottom"},
+}
+
+// Stores the distance an anchored configuration keeps from the edges of its parent
+type Margins struct {
+	Left   int `json:"left,omitempty"`
+	Top    int `json:"top,omitempty"`
+	Right  int `json:"right,omitempty"`
+	Bottom int `json:"bottom,omitempty"`
+}
+
+// Returns the rectangle of the size anchored inside the outer rectangle
+func (outer Rectangle) Anchor(width int, height int, anchor string, margins Margins) (Rectangle, error) {
+	edges, ok := anchors[anchor]
+	if !ok {
+		return Rectangle{}, fmt.Errorf("unknown anchor %s", anchor)
+	}
+	placed := outer.CenterIn(width, height)
+	switch edges.horizontal {
+	case "left":
+		placed.Left = outer.Left + margins.Left
+	case "right":
+		placed.Left = outer.Left + outer.Width - width - margins.Right
+	}
+	switch edges.vertical {
+	case "top":
+		placed.Top = outer.Top + margins.Top
+	case "bottom":
+		placed.Top = outer.Top + outer.Height - height - margins.Bottom
+	}
+	return placed, nil
+}
+
+// Parses a percentage such as 37.5%
+func parsePercent(text string) (float64, bool) {
+	if !strings.HasSuffix(text, percentSuffix) {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(text, percentSuffix)), 64)
+	return value, err == nil
+}
+
+// Returns the rectangle positions and sizes are relative to: the parent, or the display of a top level configuration
+func (config *Configuration) placementParent() (Rectangle, string, bool) {
+	if config.Parent != nil {
+		return *config.Parent.GetDimension(), config.Parent.GetPath(), true
+	}
+	if config.Display != nil {
+		return *config.Display.GetDimension(), "display " + config.Display.Name, true
+	}
+	return Rectangle{}, "", false
+}
+
+// Resolves the percentages and anchor of the configuration, resolveComputedValues calls it once the
+// rectangle of the parent and the size of the configuration are final
+func (config *Configuration) resolvePlacement() error {
+	if len(config.percentages) == 0 && len(config.Anchor) == 0 {
+		return nil
+	}
+	outer, outerName, ok := config.placementParent()
+	if !ok {
+		return fmt.Errorf("percentages and anchors need a parent or a display")
+	}
+	// Sizes first, so that percentage and anchored positions see the final size
+	for _, field := range []string{"width", "height", "left", "top"} {
+		percent, ok := config.percentages[field]
+		if !ok {
+			continue
+		}
+		var value float64
+		switch field {
+		case "width":
+			value = float64(outer.Width) * percent / 100
+		case "height":
+			value = float64(outer.Height) * percent / 100
+		case "left":
+			value = float64(outer.Left) + float64(outer.Width)*percent/100
+		case "top":
+			value = float64(outer.Top) + float64(outer.Height)*percent/100
+		}
+		config.setNumericField(field, math.Round(value))
+		config.recordPlacementSource(field, fmt.Sprintf("%s%s of %s", strconv.FormatFloat(percent, 'f', -1, 64), percentSuffix, outerName))
+	}
+	if len(config.Anchor) == 0 {
+		return nil
+	}
+	var margins Margins
+	if config.Margin != nil {
+		margins = *config.Margin
+	}
+	placed, err := outer.Anchor(config.Width, config.Height, config.Anchor, margins)
+	if err != nil {
+		return err
+	}
+	config.setNumericField("left", float64(placed.Left))
+	config.setNumericField("top", float64(placed.Top))
+	for _, field := range []string{"left", "top"} {
+		config.recordPlacementSource(field, fmt.Sprintf("anchor %s in %s", config.Anchor, outerName))
+	}
+	return nil
+}
+
+func (config *Configuration) recordPlacementSource(field string, description string) {
+	if config.provenance == nil {
+		config.provenance = map[string]FieldSource{}
+	}
+	config.provenance[field] = FieldSource{Layer: SourcePlacement, Name: description}
+}
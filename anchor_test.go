@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRectangle_Anchor(t *testing.T) {
+	outer := Rectangle{Left: 100, Top: 50, Width: 400, Height: 200}
+	margins := Margins{Left: 5, Top: 6, Right: 7, Bottom: 8}
+	tests := []struct {
+		anchor string
+		want   Rectangle
+	}{
+		{anchor: "top-left", want: Rectangle{Left: 105, Top: 56, Width: 100, Height: 40}},
+		{anchor: "top", want: Rectangle{Left: 250, Top: 56, Width: 100, Height: 40}},
+		{anchor: "top-right", want: Rectangle{Left: 393, Top: 56, Width: 100, Height: 40}},
+		{anchor: "left", want: Rectangle{Left: 105, Top: 130, Width: 100, Height: 40}},
+		{anchor: "center", want: Rectangle{Left: 250, Top: 130, Width: 100, Height: 40}},
+		{anchor: "right", want: Rectangle{Left: 393, Top: 130, Width: 100, Height: 40}},
+		{anchor: "bottom-left", want: Rectangle{Left: 105, Top: 202, Width: 100, Height: 40}},
+		{anchor: "bottom", want: Rectangle{Left: 250, Top: 202, Width: 100, Height: 40}},
+		{anchor: "bottom-right", want: Rectangle{Left: 393, Top: 202, Width: 100, Height: 40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.anchor, func(t *testing.T) {
+			got, err := outer.Anchor(100, 40, tt.anchor, margins)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Anchor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := outer.Anchor(100, 40, "middle", margins); err == nil {
+		t.Errorf("Anchor() error = nil, want unknown anchor")
+	}
+}
+
+func TestResolvePlacements(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"Placement.json": `{ "modules": [{ "name": "Placement", "configurations": [
+			{ "name": "Pages", "left": 100, "top": 50, "width": 400, "height": 200, "subConfigDef": [
+				{ "name": "Corner", "anchor": "bottom-right", "margin": { "right": 10, "bottom": 20 }, "width": 80, "height": 30 },
+				{ "name": "Half", "anchor": "center", "width": "50%", "height": "25%" },
+				{ "name": "Offset", "left": "25%", "top": "10%", "width": "12.5%", "subConfigDef": [{ "name": "Child" }] }
+			] }
+		] }] }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address string
+		want    Rectangle
+	}{
+		{address: "Placement/Pages/Corner", want: Rectangle{Left: 410, Top: 200, Width: 80, Height: 30}},
+		{address: "Placement/Pages/Half", want: Rectangle{Left: 200, Top: 125, Width: 200, Height: 50}},
+		{address: "Placement/Pages/Offset", want: Rectangle{Left: 200, Top: 70, Width: 50, Height: 200}},
+		// The child inherits the placed values of its parent
+		{address: "Placement/Pages/Offset/Child", want: Rectangle{Left: 200, Top: 70, Width: 50, Height: 200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			match, err := registry.Resolve(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := *match.Configuration.GetDimension(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDimension() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	match, _ := registry.Resolve("Placement/Pages/Corner")
+	explanation, err := match.Configuration.Explain("left")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(explanation, "anchor bottom-right in Placement/Pages") {
+		t.Errorf("Explain() = %v, want the anchor", explanation)
+	}
+}
+
+func TestResolveComputedValues_PlacementsAndExpressions(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"Mixed.json": `{ "modules": [{ "name": "Mixed", "configurations": [
+			{ "name": "Pages", "left": 0, "top": 0, "width": 600, "height": 600, "subConfigDef": [
+				{ "name": "B", "top": "=../A.top - 30", "left": "=../A.left" },
+				{ "name": "A", "anchor": "bottom-right", "width": 100, "height": 100 },
+				{ "name": "C", "anchor": "center", "width": "=../A.width * 2", "height": "=../B.top / 4" }
+			] }
+		] }] }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		address string
+		want    Rectangle
+	}{
+		{address: "Mixed/Pages/A", want: Rectangle{Left: 500, Top: 500, Width: 100, Height: 100}},
+		// The expressions read the anchored position of A
+		{address: "Mixed/Pages/B", want: Rectangle{Left: 500, Top: 470, Width: 600, Height: 600}},
+		// The anchor centers the size the expressions computed
+		{address: "Mixed/Pages/C", want: Rectangle{Left: 200, Top: 241, Width: 200, Height: 118}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			match, err := registry.Resolve(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := *match.Configuration.GetDimension(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDimension() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	cycle := writeModuleFiles(t, map[string]string{
+		"Cycle.json": `{ "modules": [{ "name": "Cycle", "configurations": [
+			{ "name": "Pages", "width": 600, "height": 600, "subConfigDef": [
+				{ "name": "A", "anchor": "center", "width": "=../B.left", "height": 100 },
+				{ "name": "B", "left": "=../A.left" }
+			] }
+		] }] }`,
+	})
+	_, err = readModuleFiles(context.Background(), cycle, &displays)
+	if err == nil || !strings.Contains(err.Error(), "expression cycle Cycle/Pages/A.placement") {
+		t.Errorf("readModuleFiles() error = %v, want a cycle through the placement of A", err)
+	}
+}
+
+func TestResolvePlacements_UnknownAnchor(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"Test.json": `{ "modules": [{ "name": "Test", "configurations": [
+			{ "name": "A", "width": 100, "height": 100, "subConfigDef": [{ "name": "B", "anchor": "middle" }] }
+		] }] }`,
+	})
+	_, err := readModuleFiles(context.Background(), root, &displays)
+	if err == nil || !strings.Contains(err.Error(), "unknown anchor middle") {
+		t.Errorf("readModuleFiles() error = %v, want unknown anchor middle", err)
+	}
+}
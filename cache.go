@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Changes whenever the cached types change, older cache files are rebuilt
+const moduleCacheVersion = 1
+
+// The file the resolved module tree is cached in, below the cache folder
+const moduleCacheFileName = "modules.gob"
+
+// Identifies the contents of a file, the hash is only computed when the size or time changed
+type cacheFileStamp struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Hash    string
+}
+
+// Stores the resolved modules of a module file and the files its includes read
+type cacheEntry struct {
+	File         cacheFileStamp
+	Dependencies []cacheFileStamp
+	Modules      []cachedModule
+}
+
+// Stores the manifest and the resolved modules of every module file that loaded without errors
+type cacheManifest struct {
+	Version int
+	// Hashes the inputs every module depends on, the displays, the throttle type and the folders
+	Key   string
+	Files map[string]*cacheEntry
+}
+
+// Stores a resolved module in a form gob can encode, without the links between configurations. The
+// layers are stored as JSON because gob does not keep pointers to zero values
+type cachedModule struct {
+	Name             string
+	Tag              string
+	DisplayName      string
+	FileName         string
+	Category         string
+	SourceFile       string
+	Defaults         []byte
+	DisplayOverrides []byte
+	Configurations   []cachedConfiguration
+}
+
+// Stores a resolved configuration, including the state that is kept in unexported fields
+type cachedConfiguration struct {
+	Name              string
+	FileName          string
+	Opacity           float32
+	Center            bool
+	Enabled           bool
+	Left              int
+	Top               int
+	Width             int
+	Height            int
+	XOffsetStart      int
+	XOffsetFinish     int
+	YOffsetStart      int
+	YOffsetFinish     int
+	NeedsThrottleType bool
+	UseAsSwitch       bool
+	ActiveChild       string
+	Anchor            string
+	Margin            *Margins
+	Explicit          []byte
+	Provenance        map[string]FieldSource
+	ActiveIndex       int
+	CascadeEnabled    bool
+	DisabledBySwitch  string
+	Configurations    []cachedConfiguration
+}
+
+// Reuses the resolved modules of the files that did not change since the last start
+type moduleCache struct {
+	filename string
+	previous *cacheManifest
+	current  *cacheManifest
+	reused   int
+	parsed   int
+	// Set when the manifest differs from the cache file and has to be written
+	dirty bool
+}
+
+func getModuleCachePath() string {
+	return filepath.Join(getCacheBaseDirectroy(), moduleCacheFileName)
+}
+
+// Opens the cache for the module roots and displays, a missing, outdated or unreadable cache is rebuilt.
+// A rebuild ignores the cache file and replaces it
+func openModuleCache(filename string, roots []string, displays Displays, rebuild bool) *moduleCache {
+	key := moduleCacheKey(roots, displays)
+	cache := &moduleCache{filename: filename, current: &cacheManifest{Version: moduleCacheVersion, Key: key, Files: map[string]*cacheEntry{}}}
+	if rebuild {
+		logger.Log("Rebuilding the module cache")
+		return cache
+	}
+	previous, err := readCacheManifest(filename)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Log(fmt.Sprintf("Ignoring the module cache %s: %s", filename, err))
+		}
+		return cache
+	}
+	if previous.Version != moduleCacheVersion || previous.Key != key {
+		logger.Log("The displays or settings changed, rebuilding the module cache")
+		return cache
+	}
+	cache.previous = previous
+	return cache
+}
+
+func readCacheManifest(filename string) (*cacheManifest, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var manifest cacheManifest
+	if err := gob.NewDecoder(file).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// Hashes the inputs that change the resolution of every module file
+func moduleCacheKey(roots []string, displays Displays) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n%s\n%s\n%s\n", moduleCacheVersion, strings.Join(roots, "\n"), configurationInstance.FilePath, configurationInstance.GetThrottleType())
+	for i := range displays {
+		data, _ := json.Marshal(struct {
+			Display  Display
+			Explicit *ConfigurationLayer
+		}{displays[i], displays[i].explicit})
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Stamps the contents read from the file. The info must be taken before the contents are read, so a change
+// after the read shows in the time of the file and the hash is checked again
+func newFileStamp(filename string, info os.FileInfo, data []byte) cacheFileStamp {
+	hash := sha256.Sum256(data)
+	return cacheFileStamp{Path: filename, Size: info.Size(), ModTime: info.ModTime(), Hash: hex.EncodeToString(hash[:])}
+}
+
+// Reads the file and returns its contents with the stamp of the contents that were read
+func readStampedFile(ctx context.Context, filename string) ([]byte, cacheFileStamp, error) {
+	info, err := statContext(ctx, filename)
+	if err != nil {
+		return nil, cacheFileStamp{}, err
+	}
+	data, err := readFileContext(ctx, filename)
+	if err != nil {
+		return nil, cacheFileStamp{}, err
+	}
+	return data, newFileStamp(filename, info, data), nil
+}
+
+// Returns the stamp of the file, the hash of the previous stamp is reused when the size and time match
+func stampFile(ctx context.Context, filename string, previous *cacheFileStamp) (cacheFileStamp, error) {
+	info, err := statContext(ctx, filename)
+	if err != nil {
+		return cacheFileStamp{}, err
+	}
+	if previous != nil && previous.Size == info.Size() && previous.ModTime.Equal(info.ModTime()) {
+		return cacheFileStamp{Path: filename, Size: info.Size(), ModTime: info.ModTime(), Hash: previous.Hash}, nil
+	}
+	data, err := readFileContext(ctx, filename)
+	if err != nil {
+		return cacheFileStamp{}, err
+	}
+	return newFileStamp(filename, info, data), nil
+}
+
+// Returns true when the file still has the contents of the stamp, the new stamp records a changed time
+func (s *cacheFileStamp) unchanged(ctx context.Context) (cacheFileStamp, bool) {
+	current, err := stampFile(ctx, s.Path, s)
+	return current, err == nil && current.Hash == s.Hash
+}
+
+// Returns the cached modules of the file when neither the file nor the files it includes changed
+func (c *moduleCache) lookup(ctx context.Context, filePath string, displays *Displays) (Modules, bool) {
+	if c == nil || c.previous == nil {
+		return nil, false
+	}
+	entry, ok := c.previous.Files[filePath]
+	if !ok {
+		return nil, false
+	}
+	file, ok := entry.File.unchanged(ctx)
+	if !ok {
+		return nil, false
+	}
+	dependencies := make([]cacheFileStamp, len(entry.Dependencies))
+	for i := range entry.Dependencies {
+		if dependencies[i], ok = entry.Dependencies[i].unchanged(ctx); !ok {
+			return nil, false
+		}
+	}
+	modules, err := restoreModules(entry.Modules, displays)
+	if err != nil {
+		logger.Log(fmt.Sprintf("Ignoring the cached modules of %s: %s", filePath, err))
+		return nil, false
+	}
+	c.current.Files[filePath] = &cacheEntry{File: file, Dependencies: dependencies, Modules: entry.Modules}
+	c.reused++
+	if !file.ModTime.Equal(entry.File.ModTime) {
+		c.dirty = true
+	}
+	for i := range dependencies {
+		if !dependencies[i].ModTime.Equal(entry.Dependencies[i].ModTime) {
+			c.dirty = true
+		}
+	}
+	return modules, true
+}
+
+// Records the resolved modules of a file that was parsed, with the stamps of the contents that were parsed
+func (c *moduleCache) store(filePath string, file cacheFileStamp, dependencies []cacheFileStamp, modules Modules) {
+	if c == nil {
+		return
+	}
+	c.parsed++
+	c.dirty = true
+	entry := &cacheEntry{File: file, Dependencies: dependencies}
+	for i := range modules {
+		cached, err := newCachedModule(&modules[i])
+		if err != nil {
+			logger.Log(fmt.Sprintf("Not caching the modules of %s: %s", filePath, err))
+			return
+		}
+		entry.Modules = append(entry.Modules, cached)
+	}
+	c.current.Files[filePath] = entry
+}
+
+// Writes the cache when a file was parsed, touched or is gone
+func (c *moduleCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	logger.Log(fmt.Sprintf("Module cache: reused %s, parsed %s", pluralize(c.reused, "file", "files"), pluralize(c.parsed, "file", "files")))
+	if c.previous != nil && !c.dirty && len(c.previous.Files) == len(c.current.Files) {
+		return nil
+	}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(c.current); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.filename), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(c.filename, buffer.Bytes(), 0644)
+}
+
+func newCachedModule(module *Module) (cachedModule, error) {
+	cached := cachedModule{
+		Name:        module.Name,
+		Tag:         module.Tag,
+		DisplayName: module.DisplayName,
+		FileName:    module.FileName,
+		Category:    module.Category,
+		SourceFile:  module.SourceFile,
+	}
+	var err error
+	if cached.Defaults, err = json.Marshal(module.ConfigurationLayer); err != nil {
+		return cached, err
+	}
+	if cached.DisplayOverrides, err = json.Marshal(module.DisplayOverrides); err != nil {
+		return cached, err
+	}
+	cached.Configurations, err = newCachedConfigurations(module.Configurations)
+	return cached, err
+}
+
+func newCachedConfigurations(configs []Configuration) ([]cachedConfiguration, error) {
+	var cached []cachedConfiguration
+	for i := range configs {
+		config := &configs[i]
+		explicit, err := json.Marshal(config.explicit)
+		if err != nil {
+			return nil, err
+		}
+		children, err := newCachedConfigurations(config.Configurations)
+		if err != nil {
+			return nil, err
+		}
+		cached = append(cached, cachedConfiguration{
+			Name:              config.Name,
+			FileName:          config.FileName,
+			Opacity:           config.Opacity,
+			Center:            config.Center,
+			Enabled:           config.Enabled,
+			Left:              config.Left,
+			Top:               config.Top,
+			Width:             config.Width,
+			Height:            config.Height,
+			XOffsetStart:      config.XOffsetStart,
+			XOffsetFinish:     config.XOffsetFinish,
+			YOffsetStart:      config.YOffsetStart,
+			YOffsetFinish:     config.YOffsetFinish,
+			NeedsThrottleType: config.NeedsThrottleType,
+			UseAsSwitch:       config.UseAsSwitch,
+			ActiveChild:       config.ActiveChild,
+			Anchor:            config.Anchor,
+			Margin:            config.Margin,
+			Explicit:          explicit,
+			Provenance:        config.provenance,
+			ActiveIndex:       config.activeChild,
+			CascadeEnabled:    config.cascadeEnabled,
+			DisabledBySwitch:  config.disabledBySwitch,
+			Configurations:    children,
+		})
+	}
+	return cached, nil
+}
+
+// Rebuilds the modules of a file from the cache, linking the configurations and matching their displays again
+func restoreModules(cached []cachedModule, displays *Displays) (Modules, error) {
+	modules := make(Modules, len(cached))
+	for i := range cached {
+		module := &modules[i]
+		*module = Module{
+			Name:        cached[i].Name,
+			Tag:         cached[i].Tag,
+			DisplayName: cached[i].DisplayName,
+			FileName:    cached[i].FileName,
+			Category:    cached[i].Category,
+			SourceFile:  cached[i].SourceFile,
+		}
+		if err := json.Unmarshal(cached[i].Defaults, &module.ConfigurationLayer); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(cached[i].DisplayOverrides, &module.DisplayOverrides); err != nil {
+			return nil, err
+		}
+		configs, err := restoreConfigurations(cached[i].Configurations)
+		if err != nil {
+			return nil, err
+		}
+		module.Configurations = configs
+		linkConfigurations(module, nil, module.Configurations, *displays)
+	}
+	return modules, nil
+}
+
+func restoreConfigurations(cached []cachedConfiguration) ([]Configuration, error) {
+	var configs []Configuration
+	for i := range cached {
+		item := &cached[i]
+		children, err := restoreConfigurations(item.Configurations)
+		if err != nil {
+			return nil, err
+		}
+		config := Configuration{
+			Name:              item.Name,
+			FileName:          item.FileName,
+			Opacity:           item.Opacity,
+			Center:            item.Center,
+			Enabled:           item.Enabled,
+			Left:              item.Left,
+			Top:               item.Top,
+			Width:             item.Width,
+			Height:            item.Height,
+			XOffsetStart:      item.XOffsetStart,
+			XOffsetFinish:     item.XOffsetFinish,
+			YOffsetStart:      item.YOffsetStart,
+			YOffsetFinish:     item.YOffsetFinish,
+			NeedsThrottleType: item.NeedsThrottleType,
+			UseAsSwitch:       item.UseAsSwitch,
+			ActiveChild:       item.ActiveChild,
+			Anchor:            item.Anchor,
+			Margin:            item.Margin,
+			Configurations:    children,
+			provenance:        item.Provenance,
+			activeChild:       item.ActiveIndex,
+			cascadeEnabled:    item.CascadeEnabled,
+			disabledBySwitch:  item.DisabledBySwitch,
+		}
+		if err := json.Unmarshal(item.Explicit, &config.explicit); err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// Restores the module, parent and display links that the cache does not store
+func linkConfigurations(module *Module, parent *Configuration, configs []Configuration, displays Displays) {
+	for i := range configs {
+		currentConfig := &configs[i]
+		if parent == nil {
+			currentConfig.Module = module
+		} else {
+			currentConfig.Parent = parent
+		}
+		currentConfig.Display, _ = currentConfig.GetDisplayRef(displays)
+		linkConfigurations(module, currentConfig, currentConfig.Configurations, displays)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Loads the modules through the cache and saves it, returning the modules and the cache counts
+func loadCachedModules(t *testing.T, cacheFile string, root string, displays Displays, rebuild bool) (Modules, *moduleCache) {
+	t.Helper()
+	cache := openModuleCache(cacheFile, []string{root}, displays, rebuild)
+	modules, err := loadModuleFiles(context.Background(), root, &displays, cache)
+	if err != nil {
+		t.Fatalf("loadModuleFiles() error = %v", err)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	return modules, cache
+}
+
+// Returns the -dump output and the explanation of every field of the modules, to compare parsed and cached modules
+func describeModules(t *testing.T, modules Modules) string {
+	t.Helper()
+	var described []interface{}
+	for i := range modules {
+		described = append(described, newResolvedModule(&modules[i]))
+		var explain func(configs []Configuration)
+		explain = func(configs []Configuration) {
+			for j := range configs {
+				for _, field := range []string{"opacity", "enabled", "left", "top", "width", "height", "xOffsetStart", "fileName"} {
+					explanation, err := configs[j].Explain(field)
+					if err != nil {
+						t.Fatal(err)
+					}
+					described = append(described, explanation, configs[j].IsSet(field))
+				}
+				explain(configs[j].Configurations)
+			}
+		}
+		explain(modules[i].Configurations)
+	}
+	data, err := json.MarshalIndent(described, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestModuleCache(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"Shared.json": `{ "fragments": { "Pages": { "top": 0, "subConfigDef": [{ "name": "BIT", "opacity": 0 }] } } }`,
+		"Jets/F-14.json": `{ "modules": [{ "name": "F-14", "opacity": 0.75, "displayOverrides": { "LMFD": { "left": -600 } },
+			"configurations": [{ "name": "LMFD_Tomcat", "include": "Shared.json#Pages" },
+				{ "name": "RMFD_Tomcat", "useAsSwitch": true, "activeChild": "B", "subConfigDef": [{ "name": "A" }, { "name": "B" }] }] }] }`,
+		"Jets/F-16.json": `{ "modules": [{ "name": "F-16", "configurations": [{ "name": "LMFD_Viper", "width": "50%", "anchor": "center" }] }] }`,
+	})
+	cacheFile := filepath.Join(t.TempDir(), "modules.gob")
+
+	parsed, cache := loadCachedModules(t, cacheFile, root, displays, false)
+	if cache.parsed != 3 || cache.reused != 0 {
+		t.Errorf("first load parsed %d and reused %d files, want 3 and 0", cache.parsed, cache.reused)
+	}
+	cached, cache := loadCachedModules(t, cacheFile, root, displays, false)
+	if cache.parsed != 0 || cache.reused != 3 {
+		t.Errorf("second load parsed %d and reused %d files, want 0 and 3", cache.parsed, cache.reused)
+	}
+	if got, want := describeModules(t, cached), describeModules(t, parsed); got != want {
+		t.Errorf("cached modules = %v, want %v", got, want)
+	}
+	registry, err := NewModuleRegistry(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, err := registry.Resolve("F-14/RMFD_Tomcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active := match.Configuration.GetActiveChild(); active == nil || active.Name != "B" {
+		t.Errorf("GetActiveChild() = %v, want B", active)
+	}
+	if match.Configuration.Display == nil || match.Configuration.Display.Name != "RMFD" {
+		t.Errorf("Display = %v, want RMFD", match.Configuration.Display)
+	}
+
+	// Changing the included fragment parses the file that includes it again
+	shared := filepath.Join(root, "Shared.json")
+	if err := os.WriteFile(shared, []byte(`{ "fragments": { "Pages": { "top": 10, "subConfigDef": [{ "name": "BIT", "opacity": 0 }] } } }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(shared, later, later); err != nil {
+		t.Fatal(err)
+	}
+	modules, cache := loadCachedModules(t, cacheFile, root, displays, false)
+	if cache.parsed != 2 || cache.reused != 1 {
+		t.Errorf("load after an include changed parsed %d and reused %d files, want 2 and 1", cache.parsed, cache.reused)
+	}
+	registry, err = NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match, err := registry.Resolve("F-14/LMFD_Tomcat"); err != nil || match.Configuration.Top != 10 {
+		t.Errorf("Resolve() = %v, %v, want the changed top", match, err)
+	}
+
+	// Touching a file without changing it keeps the cached modules
+	if err := os.Chtimes(filepath.Join(root, "Jets", "F-16.json"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, cache = loadCachedModules(t, cacheFile, root, displays, false); cache.parsed != 0 || cache.reused != 3 {
+		t.Errorf("load after a touch parsed %d and reused %d files, want 0 and 3", cache.parsed, cache.reused)
+	}
+
+	if _, cache = loadCachedModules(t, cacheFile, root, displays, true); cache.parsed != 3 || cache.reused != 0 {
+		t.Errorf("rebuild parsed %d and reused %d files, want 3 and 0", cache.parsed, cache.reused)
+	}
+
+	// Changing a display changes every module
+	displays[2].Opacity = 0.25
+	if _, cache = loadCachedModules(t, cacheFile, root, displays, false); cache.parsed != 3 || cache.reused != 0 {
+		t.Errorf("load after a display changed parsed %d and reused %d files, want 3 and 0", cache.parsed, cache.reused)
+	}
+}
+
+func TestModuleCache_Unreadable(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"F-16.json": `{ "modules": [{ "name": "F-16", "configurations": [{ "name": "LMFD_Viper" }] }] }`,
+	})
+	cacheFile := filepath.Join(t.TempDir(), "modules.gob")
+	if err := os.WriteFile(cacheFile, []byte("not a cache"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modules, cache := loadCachedModules(t, cacheFile, root, displays, false)
+	if cache.parsed != 1 || len(modules) != 1 {
+		t.Errorf("load with an unreadable cache parsed %d files and returned %d modules, want 1 and 1", cache.parsed, len(modules))
+	}
+	manifest, err := readCacheManifest(cacheFile)
+	if err != nil {
+		t.Fatalf("readCacheManifest() error = %v", err)
+	}
+	if len(manifest.Files) != 1 {
+		t.Errorf("manifest has %d files, want 1", len(manifest.Files))
+	}
+}
+
+func TestModuleCache_EditedWhileParsed(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"F-16.json": `{ "modules": [{ "name": "F-16", "configurations": [{ "name": "LMFD_Viper", "top": 0 }] }] }`,
+	})
+	filePath := filepath.Join(root, "F-16.json")
+	includes := newIncludeResolver(context.Background(), root)
+	modules, err := readModuleFile(context.Background(), filePath, includes, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file changes after it was parsed and before the cache stores its modules
+	if err := os.WriteFile(filePath, []byte(`{ "modules": [{ "name": "F-16", "configurations": [{ "name": "LMFD_Viper", "top": 10 }] }] }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	cacheFile := filepath.Join(t.TempDir(), "modules.gob")
+	cache := openModuleCache(cacheFile, []string{root}, displays, false)
+	file, dependencies, ok := includes.fileStamps(filePath)
+	if !ok {
+		t.Fatalf("fileStamps() ok = false, want true")
+	}
+	cache.store(filePath, file, dependencies, modules)
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	modules, cache = loadCachedModules(t, cacheFile, root, displays, false)
+	if cache.parsed != 1 || cache.reused != 0 {
+		t.Errorf("load after the edit parsed %d and reused %d files, want 1 and 0", cache.parsed, cache.reused)
+	}
+	if len(modules) != 1 || modules[0].Configurations[0].Top != 10 {
+		t.Errorf("loadModuleFiles() = %v, want the edited top", modules)
+	}
+}
+
+func BenchmarkLoadModuleFiles_Cached(b *testing.B) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(b)
+	root := writeSyntheticModules(b, 200)
+	cacheFile := filepath.Join(b.TempDir(), "modules.gob")
+	cache := openModuleCache(cacheFile, []string{root}, displays, false)
+	if _, err := loadModuleFiles(context.Background(), root, &displays, cache); err != nil {
+		b.Fatal(err)
+	}
+	if err := cache.Save(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache := openModuleCache(cacheFile, []string{root}, displays, false)
+		modules, err := loadModuleFiles(context.Background(), root, &displays, cache)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if cache.reused != 200 || len(modules) != 200 {
+			b.Fatalf("reused %d files and returned %d modules, want 200", cache.reused, len(modules))
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The optional file in a module directory that describes its category
+const categoryFileName = "category.json"
+
+// Separates the directories of a category path, such as Jets\Navy
+const categorySeparator = "\\"
+
+// Stores the metadata of a category read from its category.json file
+type Category struct {
+	// The directory of the category relative to the Modules root
+	Path        string `json:"-"`
+	DisplayName string `json:"displayName"`
+	// Categories with a lower sort order are listed first, ties are sorted by display name
+	SortOrder   int    `json:"sortOrder"`
+	Description string `json:"description"`
+}
+
+// Returns the category of a file, the path of its directory relative to the Modules root
+func categoryOf(root string, filePath string) (string, error) {
+	relativePath, err := filepath.Rel(root, filepath.Dir(filePath))
+	if err != nil {
+		return "", err
+	}
+	if relativePath == "." {
+		return "", nil
+	}
+	return strings.ReplaceAll(filepath.ToSlash(relativePath), "/", categorySeparator), nil
+}
+
+// Reads every category.json below the Modules root, keyed by category path. When the context is done the
+// categories read so far are returned with a CancelledError
+func readCategoryFiles(ctx context.Context, startingPath string) (map[string]*Category, error) {
+	categories := map[string]*Category{}
+	var failures ModuleLoadErrors
+	err := walkContext(ctx, startingPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		// Unreadable directories are reported by readModuleFiles
+		if err != nil {
+			if fileInfo != nil && fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fileInfo.IsDir() || filepath.Base(filePath) != categoryFileName {
+			return nil
+		}
+		category, err := readCategoryFile(ctx, startingPath, filePath)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			failures = append(failures, &ModuleFileError{FilePath: filePath, Err: err})
+			return nil
+		}
+		categories[category.Path] = category
+		return nil
+	})
+	if ctx.Err() != nil {
+		return categories, &CancelledError{Operation: "category files below " + startingPath, Progress: pluralize(len(categories), "category", "categories"), Err: ctx.Err()}
+	}
+	if err != nil {
+		return categories, err
+	}
+	if len(failures) > 0 {
+		return categories, failures
+	}
+	return categories, nil
+}
+
+// Reads the category files of every root, the category file of an earlier root wins
+func readCategoryRoots(ctx context.Context, roots []string) (map[string]*Category, error) {
+	categories := map[string]*Category{}
+	var failures ModuleLoadErrors
+	for _, root := range roots {
+		rootCategories, err := readCategoryFiles(ctx, root)
+		var rootFailures ModuleLoadErrors
+		var cancelled *CancelledError
+		if err != nil && !errors.As(err, &rootFailures) && !errors.As(err, &cancelled) {
+			return categories, err
+		}
+		for path, category := range rootCategories {
+			if _, ok := categories[path]; !ok {
+				categories[path] = category
+			}
+		}
+		failures = append(failures, rootFailures...)
+		if cancelled != nil {
+			cancelled.Progress = pluralize(len(categories), "category", "categories")
+			return categories, cancelled
+		}
+	}
+	if len(failures) > 0 {
+		return categories, failures
+	}
+	return categories, nil
+}
+
+func readCategoryFile(ctx context.Context, startingPath string, filePath string) (*Category, error) {
+	data, err := readFileContext(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	category := &Category{}
+	if err := json.Unmarshal(data, category); err != nil {
+		return nil, err
+	}
+	category.Path, err = categoryOf(startingPath, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Returns the last directory of the category path
+func categoryBaseName(path string) string {
+	return path[strings.LastIndex(path, categorySeparator)+1:]
+}
+
+// Returns the parent of the category path, the root category is empty
+func categoryParent(path string) string {
+	index := strings.LastIndex(path, categorySeparator)
+	if index < 0 {
+		return ""
+	}
+	return path[:index]
+}
+
+// Determines if category a is listed before category b, a parent is listed before its children
+func (r *ModuleRegistry) categoryLess(a string, b string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == 0 && len(b) > 0
+	}
+	aSegments := strings.Split(a, categorySeparator)
+	bSegments := strings.Split(b, categorySeparator)
+	for i := 0; i < len(aSegments) && i < len(bSegments); i++ {
+		if aSegments[i] == bSegments[i] {
+			continue
+		}
+		aCategory := r.GetCategory(strings.Join(aSegments[:i+1], categorySeparator))
+		bCategory := r.GetCategory(strings.Join(bSegments[:i+1], categorySeparator))
+		if aCategory.SortOrder != bCategory.SortOrder {
+			return aCategory.SortOrder < bCategory.SortOrder
+		}
+		if aCategory.DisplayName != bCategory.DisplayName {
+			return aCategory.DisplayName < bCategory.DisplayName
+		}
+		return aSegments[i] < bSegments[i]
+	}
+	return len(aSegments) < len(bSegments)
+}
+
+// Stores a category of the tree printed by -tree
+type categoryNode struct {
+	category *Category
+	children []*categoryNode
+	modules  []*Module
+}
+
+// Builds the category tree holding every module, categories without a module of their own are kept when a child has one
+func (r *ModuleRegistry) categoryTree() *categoryNode {
+	nodes := map[string]*categoryNode{"": {category: r.GetCategory("")}}
+	var nodeFor func(path string) *categoryNode
+	nodeFor = func(path string) *categoryNode {
+		if node, ok := nodes[path]; ok {
+			return node
+		}
+		node := &categoryNode{category: r.GetCategory(path)}
+		nodes[path] = node
+		parent := nodeFor(categoryParent(path))
+		parent.children = append(parent.children, node)
+		return node
+	}
+	for _, path := range r.Categories() {
+		node := nodeFor(path)
+		node.modules = r.GetByCategory(path)
+	}
+	for _, node := range nodes {
+		sort.Slice(node.children, func(i, j int) bool {
+			return r.categoryLess(node.children[i].category.Path, node.children[j].category.Path)
+		})
+	}
+	return nodes[""]
+}
+
+// Prints the categories, modules and configurations as an indented tree
+func printTree(registry *ModuleRegistry, w io.Writer) {
+	printCategoryNode(registry.categoryTree(), w, 0)
+}
+
+func printCategoryNode(node *categoryNode, w io.Writer, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, m := range node.modules {
+		fmt.Fprintf(w, "%s%s\t%s\n", indent, m.Name, m.DisplayName)
+		printConfigurationTree(m.Configurations, w, depth+1)
+	}
+	for _, child := range node.children {
+		if len(child.category.Description) > 0 {
+			fmt.Fprintf(w, "%s%s - %s\n", indent, child.category.DisplayName, child.category.Description)
+		} else {
+			fmt.Fprintf(w, "%s%s\n", indent, child.category.DisplayName)
+		}
+		printCategoryNode(child, w, depth+1)
+	}
+}
+
+func printConfigurationTree(configs []Configuration, w io.Writer, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for i := range configs {
+		fmt.Fprintf(w, "%s%s\n", indent, configs[i].Name)
+		printConfigurationTree(configs[i].Configurations, w, depth+1)
+	}
+}
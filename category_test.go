@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintTree(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"Jets/category.json":             `{ "displayName": "Fixed Wing", "sortOrder": 2, "description": "Jets and props" }`,
+		"Jets/Navy/F-14.json":            `{ "modules": [{ "name": "F-14", "displayName": "Tomcat", "configurations": [{ "name": "LMFD", "subConfigDef": [{ "name": "BIT" }] }] }] }`,
+		"Jets/Air Force/A-10.json":       `{ "modules": [{ "name": "A-10", "displayName": "Warthog" }] }`,
+		"Jets/Air Force/category.json":   `{ "displayName": "USAF" }`,
+		"Helicopters/category.json":      `{ "displayName": "Rotary Wing", "sortOrder": 1 }`,
+		"Helicopters/UH-1H.json":         `{ "modules": [{ "name": "UH-1H", "displayName": "Huey" }] }`,
+		"Root.json":                      `{ "modules": [{ "name": "Root", "displayName": "Top level" }] }`,
+		"Broken/category.json":           `{ "sortOrder": "first" }`,
+		"Broken/Broken.json":             `{ "modules": [{ "name": "Broken" }] }`,
+		"Jets/Navy/Unused/category.json": `{ "displayName": "Nothing here" }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	categories, err := readCategoryFiles(context.Background(), root)
+	var failures ModuleLoadErrors
+	if !errors.As(err, &failures) || len(failures) != 1 || !strings.HasSuffix(failures[0].FilePath, "category.json") {
+		t.Errorf("readCategoryFiles() error = %v, want the broken category.json", err)
+	}
+	registry.SetCategories(categories)
+
+	wantCategories := []string{"", "Broken", "Helicopters", "Jets\\Navy", "Jets\\Air Force"}
+	if got := registry.Categories(); !reflect.DeepEqual(got, wantCategories) {
+		t.Errorf("Categories() = %v, want %v", got, wantCategories)
+	}
+
+	var sb strings.Builder
+	printTree(registry, &sb)
+	want := strings.Join([]string{
+		"Root\tTop level",
+		"Broken",
+		"  Broken\t",
+		"Rotary Wing",
+		"  UH-1H\tHuey",
+		"Fixed Wing - Jets and props",
+		"  Navy",
+		"    F-14\tTomcat",
+		"      LMFD",
+		"        BIT",
+		"  USAF",
+		"    A-10\tWarthog",
+		"",
+	}, "\n")
+	if got := sb.String(); got != want {
+		t.Errorf("printTree() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -10,15 +10,22 @@ import (
 )
 
 type MfdConfig struct {
-	DisplayConfigurationFile string `json:"displayConfigurationFile"`
-	DefaultConfiguration     string `json:"defaultConfiguration"`
-	DcsSavedGamesPath        string `json:"dcsSavedGamesPath"`
-	SaveCroppedImages        bool   `json:"saveCroppedImages"`
-	Modules                  string `json:"modules"`
-	FilePath                 string `json:"filePath"`
-	UseCougar                bool   `json:"useCougar"`
-	ShowRulers               bool   `json:"showRulers"`
-	RulerSize                int    `json:"rulerSize"`
+	DisplayConfigurationFile string       `json:"displayConfigurationFile"`
+	DefaultConfiguration     string       `json:"defaultConfiguration"`
+	DcsSavedGamesPath        string       `json:"dcsSavedGamesPath"`
+	SaveCroppedImages        bool         `json:"saveCroppedImages"`
+	Modules                  string       `json:"modules"`
+	FilePath                 string       `json:"filePath"`
+	UseCougar                bool         `json:"useCougar"`
+	ThrottleType             ThrottleType `json:"throttleType,omitempty"`
+	ShowRulers               bool         `json:"showRulers"`
+	RulerSize                int          `json:"rulerSize"`
+	// More module folders searched after the modules folder, in order of precedence
+	ModuleRoots []string `json:"moduleRoots,omitempty"`
+	// Follows symlinked directories below the module roots
+	FollowSymlinks bool `json:"followSymlinks,omitempty"`
+	// The number of directory levels searched below a module root, 1 only reads the root and 0 has no limit
+	MaxModuleDepth int `json:"maxModuleDepth,omitempty"`
 }
 
 // LoadConfig loads the configuration from a JSON file.
@@ -46,6 +53,9 @@ func fixupConfigurationPaths(config *MfdConfig) {
 	config.DcsSavedGamesPath = strings.ReplaceAll(os.ExpandEnv(config.FilePath), "/", "\\")
 	config.DisplayConfigurationFile = strings.ReplaceAll(os.ExpandEnv(config.DisplayConfigurationFile), "/", "\\")
 	config.Modules = strings.ReplaceAll(os.ExpandEnv(config.Modules), "/", "\\")
+	for i := range config.ModuleRoots {
+		config.ModuleRoots[i] = strings.ReplaceAll(os.ExpandEnv(config.ModuleRoots[i]), "/", "\\")
+	}
 }
 
 func getCacheBaseDirectroy() string {
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+)
+
+// Wraps the context error of a load that stopped early, the results returned with it are partial
+type CancelledError struct {
+	// What was being loaded, such as "module files below C:\MFDMF\Modules"
+	Operation string
+	// How much was loaded before the load stopped
+	Progress string
+	Err      error
+}
+
+func (e *CancelledError) Error() string {
+	reason := "was cancelled"
+	if e.Err == context.DeadlineExceeded {
+		reason = "timed out"
+	}
+	if len(e.Progress) > 0 {
+		return fmt.Sprintf("loading %s %s after %s", e.Operation, reason, e.Progress)
+	}
+	return fmt.Sprintf("loading %s %s", e.Operation, reason)
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// Reads the file on its own goroutine so a read from a hung drive does not outlive the context. The
+// abandoned read finishes in the background and its result is dropped
+func readFileContext(ctx context.Context, filename string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := os.ReadFile(filename)
+		done <- result{data, err}
+	}()
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Returns the file info on its own goroutine, like readFileContext
+func statContext(ctx context.Context, filename string) (os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type result struct {
+		info os.FileInfo
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		info, err := os.Stat(filename)
+		done <- result{info, err}
+	}()
+	select {
+	case r := <-done:
+		return r.info, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Reads and decodes the image on its own goroutine, like readFileContext
+func decodeImageContext(ctx context.Context, filename string) (image.Image, error) {
+	data, err := readFileContext(ctx, filename)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, &CancelledError{Operation: filename, Err: ctx.Err()}
+		}
+		return nil, err
+	}
+	type result struct {
+		img image.Image
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			err = fmt.Errorf("%s: %w", filename, err)
+		}
+		done <- result{img, err}
+	}()
+	select {
+	case r := <-done:
+		return r.img, r.err
+	case <-ctx.Done():
+		return nil, &CancelledError{Operation: filename, Err: ctx.Err()}
+	}
+}
+
+// Walks the module root like walkModuleTree and returns the context error as soon as the context is done, even
+// while the walk is blocked on the file system. The walk function runs on the calling goroutine, so it
+// never runs after walkContext returned
+func walkContext(ctx context.Context, root string, walkFn filepath.WalkFunc) error {
+	type visit struct {
+		path  string
+		info  os.FileInfo
+		err   error
+		reply chan error
+	}
+	visits := make(chan visit)
+	finished := make(chan error, 1)
+	walker := newModuleWalker(func(path string, info os.FileInfo, err error) error {
+		reply := make(chan error, 1)
+		select {
+		case visits <- visit{path, info, err, reply}:
+			return <-reply
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	go func() {
+		finished <- walker.Walk(root)
+	}()
+	for {
+		select {
+		case v := <-visits:
+			err := walkFn(v.path, v.info, v.err)
+			if err == nil {
+				err = ctx.Err()
+			}
+			v.reply <- err
+		case err := <-finished:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWalkContext_StopsWhenCancelled(t *testing.T) {
+	files := map[string]string{}
+	for i := 0; i < 10; i++ {
+		files[fmt.Sprintf("Module%d.json", i)] = `{}`
+	}
+	root := writeModuleFiles(t, files)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	visited := 0
+	err := walkContext(ctx, root, func(path string, info os.FileInfo, err error) error {
+		visited++
+		if visited == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("walkContext() error = %v, want context.Canceled", err)
+	}
+	if visited != 3 {
+		t.Errorf("walkContext() visited %d entries, want 3", visited)
+	}
+}
+
+func TestReadModuleFiles_Cancelled(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"F-16.json": `{ "modules": [{ "name": "F-16", "configurations": [{ "name": "LMFD_Viper" }] }] }`,
+	})
+
+	tests := []struct {
+		name    string
+		ctx     func() (context.Context, context.CancelFunc)
+		want    error
+		message string
+	}{
+		{
+			name: "cancelled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			want:    context.Canceled,
+			message: "was cancelled after 0 modules",
+		},
+		{
+			name: "timed out",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			},
+			want:    context.DeadlineExceeded,
+			message: "timed out after 0 modules",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			modules, err := readModuleFiles(ctx, root, &displays)
+			var cancelled *CancelledError
+			if !errors.As(err, &cancelled) || !errors.Is(err, tt.want) {
+				t.Fatalf("readModuleFiles() error = %v, want a CancelledError for %v", err, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("readModuleFiles() error = %v, want %v", err, tt.message)
+			}
+			if len(modules) != 0 {
+				t.Errorf("readModuleFiles() returned %d modules, want 0", len(modules))
+			}
+		})
+	}
+}
+
+// A context that is cancelled by the check after the given number of checks of its error
+type countdownContext struct {
+	context.Context
+	cancel    context.CancelFunc
+	mu        sync.Mutex
+	remaining int
+}
+
+func newCountdownContext(checks int) *countdownContext {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &countdownContext{Context: ctx, cancel: cancel, remaining: checks}
+}
+
+func (c *countdownContext) Err() error {
+	c.mu.Lock()
+	c.remaining--
+	if c.remaining < 0 {
+		c.cancel()
+	}
+	c.mu.Unlock()
+	return c.Context.Err()
+}
+
+func TestReadModuleFiles_CancelledDuringWalk(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	files := map[string]string{}
+	for i := 0; i < 10; i++ {
+		files[fmt.Sprintf("Module%d.json", i)] = fmt.Sprintf(`{ "modules": [{ "name": "Module%d", "configurations": [{ "name": "LMFD_Module%d" }] }] }`, i, i)
+	}
+	root := writeModuleFiles(t, files)
+
+	ctx := newCountdownContext(20)
+	defer ctx.cancel()
+	modules, err := readModuleFiles(ctx, root, &displays)
+	var cancelled *CancelledError
+	if !errors.As(err, &cancelled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("readModuleFiles() error = %v, want a CancelledError for %v", err, context.Canceled)
+	}
+	if len(modules) == 0 || len(modules) >= len(files) {
+		t.Fatalf("readModuleFiles() returned %d modules, want some of %d", len(modules), len(files))
+	}
+	if want := fmt.Sprintf("was cancelled after %s", pluralize(len(modules), "module", "modules")); !strings.Contains(err.Error(), want) {
+		t.Errorf("readModuleFiles() error = %v, want %v", err, want)
+	}
+	for i, module := range modules {
+		if want := fmt.Sprintf("Module%d", i); module.Name != want {
+			t.Errorf("readModuleFiles()[%d] = %s, want %s", i, module.Name, want)
+		}
+	}
+}
+
+func TestReadFileContext(t *testing.T) {
+	root := writeModuleFiles(t, map[string]string{"displays.json": `[]`})
+	data, err := readFileContext(context.Background(), filepath.Join(root, "displays.json"))
+	if err != nil || string(data) != `[]` {
+		t.Errorf("readFileContext() = %q, %v, want []", data, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := readFileContext(ctx, filepath.Join(root, "displays.json")); !errors.Is(err, context.Canceled) {
+		t.Errorf("readFileContext() error = %v, want context.Canceled", err)
+	}
+}
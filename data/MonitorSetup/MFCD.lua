@@ -0,0 +1,36 @@
+_  = function(p) return p; end;
+name = _('Camera + LEFT and RIGHT MFCD');
+Description = 'Main camera with the left and right MFCDs on the auxiliary monitor'
+Viewports =
+{
+     Center =
+     {
+          x = 0;
+          y = 0;
+          width  = screen.width;
+          height = screen.height;
+          viewDx = 0;
+          viewDy = 0;
+          aspect = screen.aspect;
+     }
+}
+
+--[[ The MFCDs share the auxiliary monitor
+     which starts right of the main monitor ]]
+LEFT_MFCD =
+{
+     x = screen.width + 1;
+     y = 0;
+     width = 600;
+     height = 600;
+}
+
+RIGHT_MFCD =
+{
+     x = 3161; y = 0; width = 600; height = 600; -- next to the left MFCD
+}
+
+CENTER_MFCD = { x = 2561, y = 600 + 1, width = 0x258, height = 2 * 300 }
+
+UIMainView = Viewports.Center
+GU_MAIN_VIEWPORT = Viewports.Center
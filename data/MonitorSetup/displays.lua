@@ -0,0 +1,35 @@
+_  = function(p) return p; end;
+name = _("displays");
+Description = "Generated by load_mfd_config from displays.json"
+Viewports =
+{
+	Center =
+	{
+		x = 0;
+		y = 0;
+		width = 2560;
+		height = 1440;
+		viewDx = 0;
+		viewDy = 0;
+		aspect = 2560 / 1440;
+	}
+}
+
+LEFT_MFCD =
+{
+	x = 2561;
+	y = 0;
+	width = 600;
+	height = 600;
+}
+
+RIGHT_MFCD =
+{
+	x = 3161;
+	y = 0;
+	width = 600;
+	height = 600;
+}
+
+UIMainView = Viewports.Center
+GU_MAIN_VIEWPORT = Viewports.Center
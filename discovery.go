@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// The optional file in a module directory that lists the files and directories the walk skips
+const ignoreFileName = ".mfdignore"
+
+// Returns the module roots in order of precedence, the modules folder comes first and the moduleRoots
+// follow. A module in an earlier root shadows a module of the same name in a later root
+func (config *MfdConfig) GetModuleRoots() []string {
+	var roots []string
+	seen := map[string]bool{}
+	for _, root := range append([]string{config.Modules}, config.ModuleRoots...) {
+		if len(root) == 0 || seen[root] {
+			continue
+		}
+		seen[root] = true
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// A glob pattern of an ignore file
+type ignorePattern struct {
+	pattern string
+	// Patterns with a slash are matched against the path relative to the ignore file, the others against the name
+	anchored bool
+	// Patterns ending in a slash only match directories
+	directoryOnly bool
+	// Patterns starting with ! include a path an earlier pattern ignored
+	negated bool
+}
+
+// The patterns of an ignore file, they apply to the directory of the file and below
+type ignoreFile struct {
+	directory string
+	patterns  []ignorePattern
+}
+
+// Parses an ignore file, one glob pattern per line. Blank lines and lines starting with # are skipped
+func parseIgnoreFile(directory string, data []byte) (*ignoreFile, error) {
+	ignores := &ignoreFile{directory: directory}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		pattern := ignorePattern{}
+		if strings.HasPrefix(text, "!") {
+			pattern.negated = true
+			text = text[1:]
+		}
+		text = strings.ReplaceAll(text, "\\", "/")
+		if strings.HasSuffix(text, "/") {
+			pattern.directoryOnly = true
+			text = strings.TrimRight(text, "/")
+		}
+		if strings.HasPrefix(text, "/") {
+			pattern.anchored = true
+			text = strings.TrimLeft(text, "/")
+		}
+		pattern.anchored = pattern.anchored || strings.Contains(text, "/")
+		if _, err := path.Match(text, ""); err != nil || len(text) == 0 {
+			return nil, fmt.Errorf("line %d: %q is not a valid pattern", line, scanner.Text())
+		}
+		pattern.pattern = text
+		ignores.patterns = append(ignores.patterns, pattern)
+	}
+	return ignores, scanner.Err()
+}
+
+// Returns whether the path is ignored and whether a pattern matched it, the last matching pattern wins
+func (f *ignoreFile) match(filePath string, isDir bool) (ignored bool, matched bool) {
+	relativePath, err := filepath.Rel(f.directory, filePath)
+	if err != nil {
+		return false, false
+	}
+	relativePath = filepath.ToSlash(relativePath)
+	for _, pattern := range f.patterns {
+		if pattern.directoryOnly && !isDir {
+			continue
+		}
+		name := path.Base(relativePath)
+		if pattern.anchored {
+			name = relativePath
+		}
+		if ok, _ := path.Match(pattern.pattern, name); ok {
+			ignored, matched = !pattern.negated, true
+		}
+	}
+	return ignored, matched
+}
+
+// Walks a module root like filepath.Walk, skipping what the ignore files name and the directories below
+// maxModuleDepth. Symlinked directories are followed when followSymlinks is set
+type moduleWalker struct {
+	walkFn         filepath.WalkFunc
+	followSymlinks bool
+	maxDepth       int
+	// The ignore files of the directory being walked and its parents, outermost first
+	ignores []*ignoreFile
+	// The real paths of the directory being walked and its parents, a symlink to one of them is a loop
+	ancestors map[string]bool
+	// The real paths of every directory walked, mapped to the path they were walked through
+	visited map[string]string
+}
+
+// Walks the module root with the discovery settings of the configuration
+func walkModuleTree(root string, walkFn filepath.WalkFunc) error {
+	return newModuleWalker(walkFn).Walk(root)
+}
+
+// Reads the discovery settings of the configuration, the walk can then run on another goroutine
+func newModuleWalker(walkFn filepath.WalkFunc) *moduleWalker {
+	w := &moduleWalker{walkFn: walkFn, ancestors: map[string]bool{}, visited: map[string]string{}}
+	if configurationInstance != nil {
+		w.followSymlinks = configurationInstance.FollowSymlinks
+		w.maxDepth = configurationInstance.MaxModuleDepth
+	}
+	return w
+}
+
+func (w *moduleWalker) Walk(root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		err = w.walkFn(root, nil, err)
+	} else {
+		err = w.walk(root, info, 0)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (w *moduleWalker) walk(filePath string, info os.FileInfo, depth int) error {
+	if !info.IsDir() {
+		return w.walkFn(filePath, info, nil)
+	}
+	realPath, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		return w.walkFn(filePath, info, err)
+	}
+	if w.ancestors[realPath] {
+		return w.walkFn(filePath, info, fmt.Errorf("symlink loop, %s is a parent of the link", realPath))
+	}
+	if first, ok := w.visited[realPath]; ok {
+		logger.Log(fmt.Sprintf("Skipping %s, its directory was already walked through %s", filePath, first))
+		return nil
+	}
+	if err := w.walkFn(filePath, info, nil); err != nil {
+		return err
+	}
+	w.visited[realPath] = filePath
+	w.ancestors[realPath] = true
+	defer delete(w.ancestors, realPath)
+
+	ignoreCount := len(w.ignores)
+	defer func() { w.ignores = w.ignores[:ignoreCount] }()
+	if data, err := os.ReadFile(filepath.Join(filePath, ignoreFileName)); err == nil {
+		ignores, err := parseIgnoreFile(filePath, data)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", ignoreFileName, err)
+			if err := w.walkFn(filepath.Join(filePath, ignoreFileName), nil, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+		} else {
+			w.ignores = append(w.ignores, ignores)
+		}
+	} else if !os.IsNotExist(err) {
+		if err := w.walkFn(filepath.Join(filePath, ignoreFileName), nil, err); err != nil && err != filepath.SkipDir {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(filePath)
+	if err != nil {
+		if err := w.walkFn(filePath, info, err); err != nil && err != filepath.SkipDir {
+			return err
+		}
+		return nil
+	}
+	for _, entry := range entries {
+		entryPath := filepath.Join(filePath, entry.Name())
+		entryInfo, err := entry.Info()
+		if err != nil {
+			if err := w.walkFn(entryPath, nil, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if w.followSymlinks && entryInfo.Mode()&os.ModeSymlink != 0 {
+			if entryInfo, err = os.Stat(entryPath); err != nil {
+				if err := w.walkFn(entryPath, nil, err); err != nil && err != filepath.SkipDir {
+					return err
+				}
+				continue
+			}
+		}
+		if w.ignored(entryPath, entryInfo.IsDir()) {
+			continue
+		}
+		if entryInfo.IsDir() && w.maxDepth > 0 && depth+1 >= w.maxDepth {
+			logger.Log(fmt.Sprintf("Skipping %s, it is deeper than the maximum module depth of %d", entryPath, w.maxDepth))
+			continue
+		}
+		if err := w.walk(entryPath, entryInfo, depth+1); err != nil {
+			if err == filepath.SkipDir {
+				if entryInfo.IsDir() {
+					continue
+				}
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns true when the ignore files of the walked directories ignore the path, inner files win
+func (w *moduleWalker) ignored(filePath string, isDir bool) bool {
+	ignored := false
+	for _, ignores := range w.ignores {
+		if entryIgnored, matched := ignores.match(filePath, isDir); matched {
+			ignored = entryIgnored
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Returns the module files the walk of the root reads, relative to the root
+func walkedFiles(t *testing.T, root string) []string {
+	t.Helper()
+	var files []string
+	err := walkModuleTree(root, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			files = append(files, "error: "+err.Error())
+			if fileInfo != nil && fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fileInfo.IsDir() && filepath.Ext(filePath) == ".json" {
+			relativePath, _ := filepath.Rel(root, filePath)
+			files = append(files, filepath.ToSlash(relativePath))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkModuleTree() error = %v", err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestIgnoreFile_Match(t *testing.T) {
+	ignores, err := parseIgnoreFile("root", []byte(strings.Join([]string{
+		"# Schemas and backups are not module files",
+		"*.schema.json",
+		"*.bak.json",
+		"",
+		"Drafts/",
+		"/Old.json",
+		"Jets/Test*.json",
+		"!Jets/TestPilot.json",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "modules.schema.json", want: true},
+		{path: "Jets/F-14.bak.json", want: true},
+		{path: "Jets/F-14.json", want: false},
+		{path: "Drafts", isDir: true, want: true},
+		{path: "Jets/Drafts", isDir: true, want: true},
+		{path: "Drafts", isDir: false, want: false},
+		{path: "Old.json", want: true},
+		{path: "Jets/Old.json", want: false},
+		{path: "Jets/Test1.json", want: true},
+		{path: "Jets/TestPilot.json", want: false},
+		{path: "Helos/Jets/Test1.json", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got, _ := ignores.match(filepath.Join("root", filepath.FromSlash(tt.path)), tt.isDir); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseIgnoreFile("root", []byte("Jets/[")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("parseIgnoreFile() error = %v, want a bad pattern on line 1", err)
+	}
+}
+
+func TestWalkModuleTree_IgnoreFiles(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	root := writeModuleFiles(t, map[string]string{
+		".mfdignore":              "*.schema.json\nBackup/",
+		"modules.schema.json":     `{}`,
+		"F-16.json":               `{}`,
+		"Backup/F-16.json":        `{}`,
+		"Jets/F-14.json":          `{}`,
+		"Jets/.mfdignore":         "*.json\n!F-14.json",
+		"Jets/F-14 old.json":      `{}`,
+		"Jets/Navy/F-18.json":     `{}`,
+		"Helos/AH-64.schema.json": `{}`,
+	})
+	want := []string{"F-16.json", "Jets/F-14.json"}
+	if got := walkedFiles(t, root); !reflect.DeepEqual(got, want) {
+		t.Errorf("walkModuleTree() = %v, want %v", got, want)
+	}
+}
+
+func TestWalkModuleTree_MaxDepth(t *testing.T) {
+	root := writeModuleFiles(t, map[string]string{
+		"A.json":       `{}`,
+		"B/B.json":     `{}`,
+		"B/C/C.json":   `{}`,
+		"B/C/D/D.json": `{}`,
+	})
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{maxDepth: 0, want: []string{"A.json", "B/B.json", "B/C/C.json", "B/C/D/D.json"}},
+		{maxDepth: 1, want: []string{"A.json"}},
+		{maxDepth: 2, want: []string{"A.json", "B/B.json"}},
+		{maxDepth: 3, want: []string{"A.json", "B/B.json", "B/C/C.json"}},
+	}
+	for _, tt := range tests {
+		configurationInstance = &MfdConfig{MaxModuleDepth: tt.maxDepth}
+		if got := walkedFiles(t, root); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("walkModuleTree() with a maximum depth of %d = %v, want %v", tt.maxDepth, got, tt.want)
+		}
+	}
+}
+
+func TestWalkModuleTree_Symlinks(t *testing.T) {
+	root := writeModuleFiles(t, map[string]string{
+		"Jets/F-14.json": `{}`,
+	})
+	shared := writeModuleFiles(t, map[string]string{
+		"AH-64.json": `{}`,
+	})
+	if err := os.Symlink(shared, filepath.Join(root, "Helos")); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+	// A link back to the root is a loop, a second link to a walked directory is skipped
+	if err := os.Symlink(root, filepath.Join(root, "Jets", "Loop")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(shared, filepath.Join(root, "Jets", "Again")); err != nil {
+		t.Fatal(err)
+	}
+
+	configurationInstance = &MfdConfig{}
+	if got, want := walkedFiles(t, root), []string{"Jets/F-14.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walkModuleTree() without followSymlinks = %v, want %v", got, want)
+	}
+
+	configurationInstance = &MfdConfig{FollowSymlinks: true}
+	got := walkedFiles(t, root)
+	if len(got) != 3 || got[0] != "Helos/AH-64.json" || got[1] != "Jets/F-14.json" || !strings.Contains(got[2], "symlink loop") {
+		t.Errorf("walkModuleTree() with followSymlinks = %v, want Helos/AH-64.json, Jets/F-14.json and a symlink loop", got)
+	}
+}
+
+func TestLoadModuleRoots(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	user := writeModuleFiles(t, map[string]string{
+		"Jets/F-14.json":     `{ "modules": [{ "name": "F-14", "displayName": "User Tomcat", "configurations": [{ "name": "LMFD_Tomcat" }] }] }`,
+		"Jets/category.json": `{ "displayName": "User Jets" }`,
+	})
+	shared := writeModuleFiles(t, map[string]string{
+		"Jets/F-14.json":      `{ "modules": [{ "name": "F-14", "displayName": "Shared Tomcat", "configurations": [{ "name": "LMFD_Tomcat" }] }] }`,
+		"Jets/F-16.json":      `{ "modules": [{ "name": "F-16", "configurations": [{ "name": "LMFD_Viper" }] }] }`,
+		"Jets/category.json":  `{ "displayName": "Shared Jets", "sortOrder": 5 }`,
+		"Helos/category.json": `{ "displayName": "Helicopters" }`,
+	})
+	roots := []string{user, shared}
+
+	modules, err := loadModuleRoots(context.Background(), roots, &displays, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		displayName string
+		root        string
+	}{
+		{name: "F-14", displayName: "User Tomcat", root: user},
+		{name: "F-16", root: shared},
+	}
+	for _, tt := range tests {
+		m, ok := registry.GetByName(tt.name)
+		if !ok {
+			t.Fatalf("GetByName(%s) found no module", tt.name)
+		}
+		if m.DisplayName != tt.displayName || m.Root != tt.root || m.Category != "Jets" {
+			t.Errorf("GetByName(%s) = %s in %s category %s, want %s in %s category Jets", tt.name, m.DisplayName, m.Root, m.Category, tt.displayName, tt.root)
+		}
+	}
+	if registry.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", registry.Len())
+	}
+
+	categories, err := readCategoryRoots(context.Background(), roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 2 || categories["Jets"].DisplayName != "User Jets" || categories["Helos"].DisplayName != "Helicopters" {
+		t.Errorf("readCategoryRoots() = %v, want the user Jets and the shared Helos", categories)
+	}
+}
+
+func TestMfdConfig_GetModuleRoots(t *testing.T) {
+	config := &MfdConfig{Modules: "C:\\MFDMF\\Modules", ModuleRoots: []string{"D:\\Shared", "", "C:\\MFDMF\\Modules", "E:\\Team"}}
+	want := []string{"C:\\MFDMF\\Modules", "D:\\Shared", "E:\\Team"}
+	if got := config.GetModuleRoots(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetModuleRoots() = %v, want %v", got, want)
+	}
+}
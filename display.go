@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"os"
+	"reflect"
 )
 
 type Display struct {
@@ -18,6 +19,103 @@ type Display struct {
 	YOffsetFinish int     `json:"yOffsetFinish,omitempty"`
 	Opacity       float32 `json:"opacity,omitempty"`
 	Enabled       bool    `json:"enabled,omitempty"`
+	Viewport      string  `json:"viewport,omitempty"`
+	// The display shows HOTAS key images that differ per throttle type
+	NeedsThrottleType bool `json:"needsThrottleType,omitempty"`
+	// The values set in the displays file, nil for displays built in code
+	explicit *ConfigurationLayer
+	// The values a module overrides and the name of that module, see Module.DisplayOverrides
+	override     *ConfigurationLayer
+	overriddenBy string
+}
+
+// Applies the values a module overrides to the display, used on the copy returned for that module's configurations
+func (d *Display) applyOverride(override *ConfigurationLayer, module string) {
+	if override.Opacity != nil {
+		d.Opacity = *override.Opacity
+	}
+	if override.Enabled != nil {
+		d.Enabled = *override.Enabled
+	}
+	if override.Center != nil {
+		d.Center = *override.Center
+	}
+	if override.Left != nil {
+		d.Left = *override.Left
+	}
+	if override.Top != nil {
+		d.Top = *override.Top
+	}
+	if override.Width != nil {
+		d.Width = *override.Width
+	}
+	if override.Height != nil {
+		d.Height = *override.Height
+	}
+	if override.XOffsetStart != nil {
+		d.XOffsetStart = *override.XOffsetStart
+	}
+	if override.XOffsetFinish != nil {
+		d.XOffsetFinish = *override.XOffsetFinish
+	}
+	if override.YOffsetStart != nil {
+		d.YOffsetStart = *override.YOffsetStart
+	}
+	if override.YOffsetFinish != nil {
+		d.YOffsetFinish = *override.YOffsetFinish
+	}
+	if override.NeedsThrottleType != nil {
+		d.NeedsThrottleType = *override.NeedsThrottleType
+	}
+	d.override = override
+	d.overriddenBy = module
+}
+
+// Decodes a display and records the values the displays file sets explicitly
+func (d *Display) UnmarshalJSON(data []byte) error {
+	type plainDisplay Display
+	if err := json.Unmarshal(data, (*plainDisplay)(d)); err != nil {
+		return err
+	}
+	var layer ConfigurationLayer
+	if err := json.Unmarshal(data, &layer); err != nil {
+		return err
+	}
+	d.explicit = &layer
+	return nil
+}
+
+// Encodes the display with every field IsSet reports, even when it is zero
+func (d Display) MarshalJSON() ([]byte, error) {
+	type plainDisplay Display
+	return marshalSetFields(plainDisplay(d), d.IsSet)
+}
+
+// Returns true when the field named by its JSON key is set. Fields a module overrides are set. The fields of a display read from a file are
+// set when the file names them, so an explicit 0 or a negative coordinate counts; the fields of a display
+// built in code are set when they are not zero
+func (d *Display) IsSet(field string) bool {
+	if d.override.has(field) {
+		return true
+	}
+	if d.explicit != nil {
+		return d.explicit.has(field)
+	}
+	value := reflect.ValueOf(d).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if value.Type().Field(i).IsExported() && jsonFieldName(value.Type().Field(i)) == field {
+			return !value.Field(i).IsZero()
+		}
+	}
+	return false
+}
+
+// Returns a pointer to the coordinate, or nil when it is not set
+func (d *Display) optional(field string, value int) *int {
+	if !d.IsSet(field) {
+		return nil
+	}
+	return &value
 }
 
 // Returns the coordinates that comprise a display area
@@ -41,19 +139,24 @@ func (d *Display) GetBaseDimension() *Rectangle {
 	return &rect
 }
 
+// Returns the DCS MonitorSetup viewport exported for the display, if any
+func (d *Display) GetViewportName() string {
+	if len(d.Viewport) > 0 {
+		return d.Viewport
+	}
+	for viewport, name := range monitorSetupDisplayNames {
+		if name == d.Name && viewport != "Center" {
+			return viewport
+		}
+	}
+	return ""
+}
+
 // SetDefaults for a single Display
 func (d *Display) SetDefaults() {
 	d.Opacity = 1.0
 	d.Center = false
 	d.Enabled = true
-	d.Left = -1
-	d.Top = -1
-	d.Width = -1
-	d.Height = -1
-	d.XOffsetStart = -1
-	d.YOffsetStart = -1
-	d.XOffsetFinish = -1
-	d.YOffsetFinish = -1
 }
 
 // Displays represents a slice of Display
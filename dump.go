@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Stores a module as it is written by -dump
+type resolvedModule struct {
+	Name           string                  `json:"name"`
+	Tag            string                  `json:"tag"`
+	DisplayName    string                  `json:"displayName"`
+	Category       string                  `json:"category"`
+	SourceFile     string                  `json:"sourceFile"`
+	Root           string                  `json:"root"`
+	Configurations []resolvedConfiguration `json:"configurations"`
+}
+
+// Stores a fully resolved configuration as it is written by -dump, every value is written even when it is zero
+type resolvedConfiguration struct {
+	Path              string                  `json:"path"`
+	Name              string                  `json:"name"`
+	FileName          string                  `json:"fileName"`
+	Display           string                  `json:"display,omitempty"`
+	Opacity           float32                 `json:"opacity"`
+	Enabled           bool                    `json:"enabled"`
+	Center            bool                    `json:"center"`
+	Left              int                     `json:"left"`
+	Top               int                     `json:"top"`
+	Width             int                     `json:"width"`
+	Height            int                     `json:"height"`
+	XOffsetStart      int                     `json:"xOffsetStart"`
+	XOffsetFinish     int                     `json:"xOffsetFinish"`
+	YOffsetStart      int                     `json:"yOffsetStart"`
+	YOffsetFinish     int                     `json:"yOffsetFinish"`
+	NeedsThrottleType bool                    `json:"needsThrottleType,omitempty"`
+	UseAsSwitch       bool                    `json:"useAsSwitch,omitempty"`
+	ActiveChild       string                  `json:"activeChild,omitempty"`
+	Configurations    []resolvedConfiguration `json:"subConfigDef,omitempty"`
+}
+
+func newResolvedModule(module *Module) resolvedModule {
+	resolved := resolvedModule{
+		Name:           module.Name,
+		Tag:            module.Tag,
+		DisplayName:    module.DisplayName,
+		Category:       module.Category,
+		SourceFile:     module.SourceFile,
+		Root:           module.Root,
+		Configurations: []resolvedConfiguration{},
+	}
+	for i := range module.Configurations {
+		resolved.Configurations = append(resolved.Configurations, newResolvedConfiguration(&module.Configurations[i]))
+	}
+	return resolved
+}
+
+func newResolvedConfiguration(config *Configuration) resolvedConfiguration {
+	resolved := resolvedConfiguration{
+		Path:              config.GetPath(),
+		Name:              config.Name,
+		FileName:          config.FileName,
+		Opacity:           config.Opacity,
+		Enabled:           config.Enabled,
+		Center:            config.Center,
+		Left:              config.Left,
+		Top:               config.Top,
+		Width:             config.Width,
+		Height:            config.Height,
+		XOffsetStart:      config.XOffsetStart,
+		XOffsetFinish:     config.XOffsetFinish,
+		YOffsetStart:      config.YOffsetStart,
+		YOffsetFinish:     config.YOffsetFinish,
+		NeedsThrottleType: config.NeedsThrottleType,
+		UseAsSwitch:       config.UseAsSwitch,
+	}
+	if active := config.GetActiveChild(); active != nil {
+		resolved.ActiveChild = active.Name
+	}
+	if config.Display != nil {
+		resolved.Display = config.Display.Name
+	}
+	for i := range config.Configurations {
+		resolved.Configurations = append(resolved.Configurations, newResolvedConfiguration(&config.Configurations[i]))
+	}
+	return resolved
+}
+
+// Returns the modules the address selects, an address that names configurations keeps only those
+// configurations and their children
+func resolveDump(registry *ModuleRegistry, address string) ([]resolvedModule, error) {
+	var modules []resolvedModule
+	if len(address) == 0 {
+		registry.ForEach(func(m *Module) bool {
+			modules = append(modules, newResolvedModule(m))
+			return true
+		})
+		return modules, nil
+	}
+	matches, err := registry.ResolveAll(address)
+	if err != nil {
+		return nil, err
+	}
+	index := map[*Module]int{}
+	for _, match := range matches {
+		i, ok := index[match.Module]
+		if !ok {
+			i = len(modules)
+			index[match.Module] = i
+			resolved := newResolvedModule(match.Module)
+			if match.Configuration != nil {
+				resolved.Configurations = []resolvedConfiguration{}
+			}
+			modules = append(modules, resolved)
+		}
+		if match.Configuration != nil {
+			modules[i].Configurations = append(modules[i].Configurations, newResolvedConfiguration(match.Configuration))
+		}
+	}
+	return modules, nil
+}
+
+// Prints the fully resolved tree of the selected modules, or of every module, as JSON
+func dumpModules(registry *ModuleRegistry, address string) error {
+	modules, err := resolveDump(registry, address)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(modules, "", "\t")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
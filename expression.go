@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Evaluates arithmetic expressions with + - * / %, parentheses, numbers and named values
+type expressionParser struct {
+	src string
+	pos int
+	// Returns the end of the name that starts at the position
+	scanName func(src string, start int) int
+	lookup   func(name string) (float64, error)
+}
+
+// Evaluates the expression, lookup supplies the value of each name it references. Names are identifiers,
+// so ${width/2} divides the width parameter
+func evaluateExpression(expression string, lookup func(name string) (float64, error)) (float64, error) {
+	return evaluate(&expressionParser{src: expression, scanName: scanIdentifier, lookup: lookup})
+}
+
+// Evaluates an expression whose names are references to configuration fields, such as ../BIT.top
+func evaluateReferenceExpression(expression string, lookup func(name string) (float64, error)) (float64, error) {
+	return evaluate(&expressionParser{src: expression, scanName: scanReference, lookup: lookup})
+}
+
+func evaluate(parser *expressionParser) (float64, error) {
+	expression := parser.src
+	value, err := parser.parseSum()
+	if err != nil {
+		return 0, fmt.Errorf("expression %q: %w", expression, err)
+	}
+	parser.skipSpace()
+	if parser.pos < len(parser.src) {
+		return 0, fmt.Errorf("expression %q: unexpected %q", expression, parser.src[parser.pos:])
+	}
+	return value, nil
+}
+
+func (p *expressionParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// Consumes the next character when it is one of the operators
+func (p *expressionParser) acceptOperator(operators string) (byte, bool) {
+	p.skipSpace()
+	if p.pos < len(p.src) && strings.IndexByte(operators, p.src[p.pos]) >= 0 {
+		p.pos++
+		return p.src[p.pos-1], true
+	}
+	return 0, false
+}
+
+func (p *expressionParser) parseSum() (float64, error) {
+	value, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		operator, ok := p.acceptOperator("+-")
+		if !ok {
+			return value, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return 0, err
+		}
+		if operator == '+' {
+			value += right
+		} else {
+			value -= right
+		}
+	}
+}
+
+func (p *expressionParser) parseProduct() (float64, error) {
+	value, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		operator, ok := p.acceptOperator("*/%")
+		if !ok {
+			return value, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch operator {
+		case '*':
+			value *= right
+		case '/', '%':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			if operator == '/' {
+				value /= right
+			} else {
+				value = float64(int(value) % int(right))
+			}
+		}
+	}
+}
+
+func (p *expressionParser) parseUnary() (float64, error) {
+	if _, ok := p.acceptOperator("-"); ok {
+		value, err := p.parseUnary()
+		return -value, err
+	}
+	if _, ok := p.acceptOperator("+"); ok {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (float64, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return 0, fmt.Errorf("unexpected end")
+	}
+	if _, ok := p.acceptOperator("("); ok {
+		value, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.acceptOperator(")"); !ok {
+			return 0, fmt.Errorf("missing )")
+		}
+		return value, nil
+	}
+	start := p.pos
+	c := rune(p.src[p.pos])
+	// A dot starts a number like .5, but also a relative name like ../BIT.top
+	if unicode.IsDigit(c) || (c == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(rune(p.src[p.pos+1]))) {
+		for p.pos < len(p.src) && (unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		return strconv.ParseFloat(p.src[start:p.pos], 64)
+	}
+	p.pos = p.scanName(p.src, start)
+	if p.pos == start {
+		return 0, fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	return p.lookup(p.src[start:p.pos])
+}
+
+// Returns the end of the identifier that starts at the position, identifiers hold letters, digits and underscores
+func scanIdentifier(src string, start int) int {
+	pos := start
+	for pos < len(src) && isIdentifierRune(rune(src[pos])) {
+		pos++
+	}
+	return pos
+}
+
+func isIdentifierRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// Returns the end of the reference that starts at the position, such as /LMFD_Pages/F-18WH.top. The path
+// segments are configuration names, which may hold dashes, or . and .. and end at a slash. The reference
+// ends after the .field that follows the path, so ../A.top/2 divides and ../A.top-30 subtracts. A name
+// without a .field is a field of the configuration, an identifier, so width/4 divides too
+func scanReference(src string, start int) int {
+	pos := start
+	if pos < len(src) && src[pos] == '/' {
+		pos++
+	}
+	for {
+		if strings.HasPrefix(src[pos:], "../") || strings.HasPrefix(src[pos:], "./") {
+			pos += strings.IndexByte(src[pos:], '/') + 1
+			continue
+		}
+		if pos+1 < len(src) && src[pos] == '.' && isIdentifierStart(rune(src[pos+1])) && pos > start {
+			// The field of the configuration the path named, such as ../.left
+			return scanIdentifier(src, pos+1)
+		}
+		segment := pos
+		for pos < len(src) && (isIdentifierRune(rune(src[pos])) || src[pos] == '-') {
+			pos++
+		}
+		if pos > segment && pos < len(src) && src[pos] == '/' {
+			pos++
+			continue
+		}
+		if pos > segment && pos+1 < len(src) && src[pos] == '.' && isIdentifierStart(rune(src[pos+1])) {
+			return scanIdentifier(src, pos+1)
+		}
+		// Without a .field the name is a bare field, which ends before a dash or slash
+		return scanIdentifier(src, start)
+	}
+}
+
+func isIdentifierStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The indentation of formatted files
+const formatIndent = "    "
+
+// The canonical order of the keys of module, configuration and display objects. Keys that are not
+// listed come after the flags in alphabetical order, followed by the lists of child objects
+var canonicalKeyOrder = []string{
+	fragmentsKey, templatesKey,
+	"name", "tag", "displayName", "description", "sortOrder", includeKey, templateKey, "with", "fileName",
+	"anchor", "margin", "left", "top", "width", "height", "center",
+	"xOffsetStart", "xOffsetFinish", "yOffsetStart", "yOffsetFinish",
+	"opacity", "enabled", "useAsSwitch", "activeChild", "needsThrottleType", "viewport",
+}
+
+// The keys of the lists of child objects, they are written last
+var childListKeys = []string{repeatKey, "parameters", "body", "each", "modules", "configurations", "subConfigDef"}
+
+// Returns the rank of the key in the canonical order, unknown keys rank between the flags and the child lists
+func canonicalKeyRank(key string) int {
+	for i, known := range canonicalKeyOrder {
+		if key == known {
+			return i
+		}
+	}
+	for i, known := range childListKeys {
+		if key == known {
+			return len(canonicalKeyOrder) + 1 + i
+		}
+	}
+	return len(canonicalKeyOrder)
+}
+
+// Returns the JSON document with canonical key order and indentation, numbers are kept as they are written
+func formatJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON document")
+	}
+	var buffer bytes.Buffer
+	if err := writeCanonical(&buffer, document, 0); err != nil {
+		return nil, err
+	}
+	buffer.WriteByte('\n')
+	return buffer.Bytes(), nil
+}
+
+func writeCanonical(buffer *bytes.Buffer, value interface{}, depth int) error {
+	indent := strings.Repeat(formatIndent, depth+1)
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if len(typed) == 0 {
+			buffer.WriteString("{}")
+			return nil
+		}
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			iRank, jRank := canonicalKeyRank(keys[i]), canonicalKeyRank(keys[j])
+			if iRank != jRank {
+				return iRank < jRank
+			}
+			return keys[i] < keys[j]
+		})
+		buffer.WriteString("{\n")
+		for i, key := range keys {
+			buffer.WriteString(indent)
+			if err := writeScalar(buffer, key); err != nil {
+				return err
+			}
+			buffer.WriteString(": ")
+			if err := writeCanonical(buffer, typed[key], depth+1); err != nil {
+				return err
+			}
+			if i < len(keys)-1 {
+				buffer.WriteByte(',')
+			}
+			buffer.WriteByte('\n')
+		}
+		buffer.WriteString(strings.Repeat(formatIndent, depth) + "}")
+	case []interface{}:
+		if len(typed) == 0 {
+			buffer.WriteString("[]")
+			return nil
+		}
+		buffer.WriteString("[\n")
+		for i, item := range typed {
+			buffer.WriteString(indent)
+			if err := writeCanonical(buffer, item, depth+1); err != nil {
+				return err
+			}
+			if i < len(typed)-1 {
+				buffer.WriteByte(',')
+			}
+			buffer.WriteByte('\n')
+		}
+		buffer.WriteString(strings.Repeat(formatIndent, depth) + "]")
+	default:
+		return writeScalar(buffer, typed)
+	}
+	return nil
+}
+
+// Writes a string, number, boolean or null without escaping HTML characters
+func writeScalar(buffer *bytes.Buffer, value interface{}) error {
+	var scalar bytes.Buffer
+	encoder := json.NewEncoder(&scalar)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	buffer.Write(bytes.TrimRight(scalar.Bytes(), "\n"))
+	return nil
+}
+
+// Formats the file, in check mode the file is left alone. Returns true when the file was not formatted
+func formatFile(filename string, check bool) (bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	formatted, err := formatJSON(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", filename, err)
+	}
+	if bytes.Equal(data, formatted) {
+		return false, nil
+	}
+	if check {
+		return true, nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return true, err
+	}
+	return true, writeFileAtomic(filename, formatted, info.Mode().Perm())
+}
+
+// Returns the display file and every JSON file below the module roots that the ignore files do not skip
+func formatTargets() ([]string, error) {
+	var files []string
+	if len(configurationInstance.DisplayConfigurationFile) > 0 {
+		files = append(files, configurationInstance.DisplayConfigurationFile)
+	}
+	for _, root := range configurationInstance.GetModuleRoots() {
+		err := walkModuleTree(root, func(filePath string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fileInfo.IsDir() && filepath.Ext(filePath) == ".json" {
+				files = append(files, filePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return files, err
+		}
+	}
+	return files, nil
+}
+
+// Formats or checks the files, returns the files that were not formatted
+func formatFiles(files []string, check bool) ([]string, error) {
+	var changed []string
+	var failures ModuleLoadErrors
+	for _, file := range files {
+		wasChanged, err := formatFile(file, check)
+		if err != nil {
+			failures = append(failures, &ModuleFileError{FilePath: file, Err: err})
+			continue
+		}
+		if wasChanged {
+			changed = append(changed, file)
+		}
+	}
+	if len(failures) > 0 {
+		return changed, failures
+	}
+	return changed, nil
+}
+
+// Runs -fmt or -check over the files named on the command line, or over the display and module files.
+// Returns false when -check found a file that is not formatted or a file could not be read
+func runFormat(files []string, check bool) bool {
+	if len(files) == 0 {
+		targets, err := formatTargets()
+		if err != nil {
+			logger.Log(fmt.Sprintf("Error: %s", err))
+			fmt.Println(err)
+			return false
+		}
+		files = targets
+	}
+	changed, err := formatFiles(files, check)
+	for _, file := range changed {
+		if check {
+			fmt.Printf("%s is not formatted\n", file)
+		} else {
+			fmt.Printf("Formatted %s\n", file)
+		}
+	}
+	if err != nil {
+		logger.Log(fmt.Sprintf("Error: %s", err))
+		fmt.Println(err)
+		return false
+	}
+	logger.Log(fmt.Sprintf("Checked %s, %d not formatted", pluralize(len(files), "file", "files"), len(changed)))
+	return !check || len(changed) == 0
+}
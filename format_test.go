@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "key order",
+			input: `{"subConfigDef":[],"enabled":true,"custom":1,"xOffsetStart":5,"width":10,"fileName":"a<b>.png","name":"BIT","alpha":"x"}`,
+			want: `{
+    "name": "BIT",
+    "fileName": "a<b>.png",
+    "width": 10,
+    "xOffsetStart": 5,
+    "enabled": true,
+    "alpha": "x",
+    "custom": 1,
+    "subConfigDef": []
+}
+`,
+		},
+		{
+			name:  "display array",
+			input: "[\r\n\t{ \"opacity\": 1.0, \"name\": \"LMFD\", \"left\": 2561 },\r\n\t{}\r\n]",
+			want: `[
+    {
+        "name": "LMFD",
+        "left": 2561,
+        "opacity": 1.0
+    },
+    {}
+]
+`,
+		},
+		{
+			name:  "escapes",
+			input: `{"fileName":"~MFDisplay_Overlays\red.png","name":"BIT_Selected"}`,
+			want: `{
+    "name": "BIT_Selected",
+    "fileName": "~MFDisplay_Overlays\red.png"
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatJSON([]byte(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("formatJSON() =\n%s\nwant\n%s", got, tt.want)
+			}
+			again, err := formatJSON(got)
+			if err != nil || string(again) != string(got) {
+				t.Errorf("formatJSON() is not idempotent:\n%s", again)
+			}
+		})
+	}
+	for _, input := range []string{`{"name": }`, `{} {}`} {
+		if _, err := formatJSON([]byte(input)); err == nil {
+			t.Errorf("formatJSON(%s) error = nil, want error", input)
+		}
+	}
+}
+
+func TestFormatFiles(t *testing.T) {
+	root := writeModuleFiles(t, map[string]string{
+		"Formatted.json":   "{\n    \"modules\": []\n}\n",
+		"Unformatted.json": `{"modules":[{"tag":"T","name":"N"}]}`,
+		"Broken.json":      `{"modules":`,
+	})
+	formatted := filepath.Join(root, "Formatted.json")
+	unformatted := filepath.Join(root, "Unformatted.json")
+	broken := filepath.Join(root, "Broken.json")
+	if err := os.Chmod(unformatted, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := formatFiles([]string{formatted, unformatted}, true)
+	if err != nil || len(changed) != 1 || changed[0] != unformatted {
+		t.Fatalf("formatFiles() check = %v, %v, want %s", changed, err, unformatted)
+	}
+	if data, _ := os.ReadFile(unformatted); string(data) != `{"modules":[{"tag":"T","name":"N"}]}` {
+		t.Errorf("formatFiles() check rewrote the file")
+	}
+
+	changed, err = formatFiles([]string{formatted, unformatted, broken}, false)
+	var failures ModuleLoadErrors
+	if !errors.As(err, &failures) || len(failures) != 1 || failures[0].FilePath != broken {
+		t.Errorf("formatFiles() error = %v, want the broken file", err)
+	}
+	if len(changed) != 1 {
+		t.Errorf("formatFiles() = %v, want 1 changed file", changed)
+	}
+	if changed, _ := formatFiles([]string{unformatted}, true); len(changed) != 0 {
+		t.Errorf("formatFiles() check after formatting = %v, want none", changed)
+	}
+	info, err := os.Stat(unformatted)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("formatFiles() mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 3 {
+		t.Errorf("formatFiles() left %d files, want 3", len(entries))
+	}
+}
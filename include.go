@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The directive that pulls a named fragment into a module or configuration, for example
+// "include": "F-14\\Tomcat.json#LMFD_Tomcat", a reference starting with # names a fragment in the same file
+const includeKey = "include"
+
+// The object of a module file that holds the named fragments other files can include
+const fragmentsKey = "fragments"
+
+// Keys whose lists are merged by configuration name rather than replaced
+var mergedListKeys = []string{"configurations", "subConfigDef"}
+
+// Expands the include directives of module files, fragments are found relative to the Modules root
+type includeResolver struct {
+	// Stops the reads of included files, the resolver lives for a single load
+	ctx   context.Context
+	root  string
+	files map[string]map[string]interface{}
+	// The other files read for the includes of the last expanded file, see fileStamps
+	used map[string]bool
+	// The stamps of the contents of every file read, as they were parsed
+	stamps map[string]cacheFileStamp
+}
+
+func newIncludeResolver(ctx context.Context, root string) *includeResolver {
+	return &includeResolver{ctx: ctx, root: root, files: map[string]map[string]interface{}{}, stamps: map[string]cacheFileStamp{}}
+}
+
+// Returns the module file data with every include directive replaced by the merged fragment
+func (r *includeResolver) ExpandFile(filename string, data []byte) ([]byte, error) {
+	r.used = map[string]bool{}
+	if !bytes.Contains(data, []byte(`"`+includeKey+`"`)) {
+		return data, nil
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	r.files[filename] = document
+	expanded, err := r.expand(filename, document, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(expanded)
+}
+
+// Reads and caches a module file referenced by an include
+func (r *includeResolver) load(filename string) (map[string]interface{}, error) {
+	if r.used != nil {
+		r.used[filename] = true
+	}
+	if document, ok := r.files[filename]; ok {
+		return document, nil
+	}
+	data, stamp, err := readStampedFile(r.ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	r.files[filename] = document
+	r.stamps[filename] = stamp
+	return document, nil
+}
+
+// Returns the stamp of the last expanded file and of the other files it includes fragments from, sorted by
+// name, as they were parsed. Returns false when a file was not read through the resolver
+func (r *includeResolver) fileStamps(filename string) (cacheFileStamp, []cacheFileStamp, bool) {
+	file, ok := r.stamps[filename]
+	if !ok {
+		return cacheFileStamp{}, nil, false
+	}
+	var files []string
+	for used := range r.used {
+		if used != filename {
+			files = append(files, used)
+		}
+	}
+	sort.Strings(files)
+	dependencies := make([]cacheFileStamp, len(files))
+	for i, used := range files {
+		if dependencies[i], ok = r.stamps[used]; !ok {
+			return file, nil, false
+		}
+	}
+	return file, dependencies, true
+}
+
+// Splits an include reference into the file and the fragment name
+func (r *includeResolver) parseReference(filename string, reference string) (string, string, error) {
+	file, fragment, found := strings.Cut(reference, "#")
+	if !found || len(fragment) == 0 {
+		return "", "", fmt.Errorf("include %q must name a fragment as file#fragment", reference)
+	}
+	if len(file) == 0 {
+		return filename, fragment, nil
+	}
+	file = strings.ReplaceAll(file, "\\", string(filepath.Separator))
+	return filepath.Join(r.root, file), fragment, nil
+}
+
+// Recursively expands includes, stack holds the file#fragment chain used to detect cycles
+func (r *includeResolver) expand(filename string, value interface{}, stack []string) (interface{}, error) {
+	switch typed := value.(type) {
+	case []interface{}:
+		expanded := make([]interface{}, len(typed))
+		for i, item := range typed {
+			itemValue, err := r.expand(filename, item, stack)
+			if err != nil {
+				return nil, err
+			}
+			expanded[i] = itemValue
+		}
+		return expanded, nil
+	case map[string]interface{}:
+		expanded := map[string]interface{}{}
+		for key, item := range typed {
+			if key == includeKey || key == fragmentsKey {
+				continue
+			}
+			itemValue, err := r.expand(filename, item, stack)
+			if err != nil {
+				return nil, err
+			}
+			expanded[key] = itemValue
+		}
+		reference, ok := typed[includeKey]
+		if !ok {
+			return expanded, nil
+		}
+		referenceText, ok := reference.(string)
+		if !ok {
+			return nil, fmt.Errorf("include must be a string")
+		}
+		fragment, err := r.includeFragment(filename, referenceText, stack)
+		if err != nil {
+			return nil, err
+		}
+		return mergeObjects(fragment, expanded), nil
+	}
+	return value, nil
+}
+
+// Loads and expands the referenced fragment
+func (r *includeResolver) includeFragment(filename string, reference string, stack []string) (map[string]interface{}, error) {
+	file, fragmentName, err := r.parseReference(filename, reference)
+	if err != nil {
+		return nil, err
+	}
+	key := file + "#" + fragmentName
+	chain := append(append([]string{}, stack...), key)
+	for i, visited := range stack {
+		if visited == key {
+			return nil, fmt.Errorf("include cycle %s", strings.Join(chain[i:], " -> "))
+		}
+	}
+	document, err := r.load(file)
+	if err != nil {
+		return nil, fmt.Errorf("include %q: %w", reference, err)
+	}
+	fragments, _ := document[fragmentsKey].(map[string]interface{})
+	fragment, ok := fragments[fragmentName].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("include %q: fragment %s is not defined in %s", reference, fragmentName, file)
+	}
+	expanded, err := r.expand(file, fragment, chain)
+	if err != nil {
+		return nil, fmt.Errorf("include %q: %s: %w", reference, file, err)
+	}
+	return expanded.(map[string]interface{}), nil
+}
+
+// Merges the including object over the fragment: its keys replace the fragment keys, except for the
+// configuration lists where entries with the same name are merged and new entries are appended
+func mergeObjects(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseList, baseIsList := merged[key].([]interface{})
+		overrideList, overrideIsList := value.([]interface{})
+		if baseIsList && overrideIsList && isMergedListKey(key) {
+			merged[key] = mergeNamedLists(baseList, overrideList)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+func isMergedListKey(key string) bool {
+	for _, listKey := range mergedListKeys {
+		if key == listKey {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeNamedLists(base []interface{}, override []interface{}) []interface{} {
+	merged := make([]interface{}, len(base))
+	copy(merged, base)
+	for _, item := range override {
+		overrideItem, ok := item.(map[string]interface{})
+		replaced := false
+		if ok {
+			for i, existing := range merged {
+				existingItem, ok := existing.(map[string]interface{})
+				if ok && existingItem["name"] != nil && existingItem["name"] == overrideItem["name"] {
+					merged[i] = mergeObjects(existingItem, overrideItem)
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
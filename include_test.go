@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeModuleFiles(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestIncludeResolver_ExpandFile(t *testing.T) {
+	root := writeModuleFiles(t, map[string]string{
+		"F-14/Tomcat.json": `{
+			"fragments": {
+				"LMFD": {
+					"name": "LMFD_Tomcat",
+					"opacity": 0.5,
+					"subConfigDef": [
+						{ "name": "BIT", "width": 210 },
+						{ "name": "SPL", "include": "#Selected" }
+					]
+				},
+				"Selected": { "name": "SPL", "width": 100, "height": 30 }
+			}
+		}`,
+	})
+	module := `{
+		"modules": [{
+			"name": "F-14RHV",
+			"configurations": [{
+				"include": "F-14\\Tomcat.json#LMFD",
+				"name": "LMFD_TomcatRIO",
+				"subConfigDef": [
+					{ "name": "BIT", "height": 469 },
+					{ "name": "NAV" }
+				]
+			}]
+		}]
+	}`
+	resolver := newIncludeResolver(context.Background(), root)
+	data, err := resolver.ExpandFile(filepath.Join(root, "F-14RIO.json"), []byte(module))
+	if err != nil {
+		t.Fatalf("ExpandFile() error = %v", err)
+	}
+
+	var jsonData struct {
+		Modules []struct {
+			Configurations []Configuration `json:"configurations"`
+		} `json:"modules"`
+	}
+	if err := json.Unmarshal(data, &jsonData); err != nil {
+		t.Fatal(err)
+	}
+	config := jsonData.Modules[0].Configurations[0]
+	if config.Name != "LMFD_TomcatRIO" || config.Opacity != 0.5 {
+		t.Errorf("ExpandFile() name = %s opacity = %v, want the including name and the fragment opacity", config.Name, config.Opacity)
+	}
+	var names []string
+	for _, sub := range config.Configurations {
+		names = append(names, sub.Name)
+	}
+	if got := strings.Join(names, ","); got != "BIT,SPL,NAV" {
+		t.Errorf("ExpandFile() sub configurations = %s, want BIT,SPL,NAV", got)
+	}
+	if bit := config.Configurations[0]; bit.Width != 210 || bit.Height != 469 {
+		t.Errorf("ExpandFile() merged BIT = %dx%d, want 210x469", bit.Width, bit.Height)
+	}
+	if spl := config.Configurations[1]; spl.Width != 100 || spl.Height != 30 {
+		t.Errorf("ExpandFile() nested include SPL = %dx%d, want 100x30", spl.Width, spl.Height)
+	}
+}
+
+func TestIncludeResolver_Errors(t *testing.T) {
+	root := writeModuleFiles(t, map[string]string{
+		"a.json": `{ "fragments": { "A": { "include": "b.json#B" } } }`,
+		"b.json": `{ "fragments": { "B": { "include": "a.json#A" } } }`,
+	})
+	tests := []struct {
+		name    string
+		include string
+		want    []string
+	}{
+		{name: "Cycle", include: "a.json#A", want: []string{"include cycle", "a.json#A -> ", "b.json#B -> "}},
+		{name: "Missing Fragment", include: "b.json#C", want: []string{`include "b.json#C"`, "fragment C is not defined in", "b.json"}},
+		{name: "Missing File", include: "c.json#C", want: []string{`include "c.json#C"`, "c.json"}},
+		{name: "No Fragment", include: "a.json", want: []string{"must name a fragment"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := `{ "modules": [{ "name": "Test", "include": "` + tt.include + `" }] }`
+			_, err := newIncludeResolver(context.Background(), root).ExpandFile(filepath.Join(root, "main.json"), []byte(module))
+			if err == nil {
+				t.Fatal("ExpandFile() error = nil, want error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ExpandFile() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
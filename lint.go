@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Stores a problem found by a lint rule
+type LintFinding struct {
+	Rule string `json:"rule"`
+	// The module file the problem was found in, empty for problems with the displays
+	File string `json:"file,omitempty"`
+	// The address of the module or configuration, or the name of the display
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (f LintFinding) String() string {
+	if len(f.File) > 0 {
+		return fmt.Sprintf("%s: %s: %s [%s]", f.File, f.Path, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("%s: %s [%s]", f.Path, f.Message, f.Rule)
+}
+
+// Stores a named lint rule, check reports the findings through report
+type lintRule struct {
+	Name        string
+	Description string
+	check       func(linter *moduleLinter)
+}
+
+// The lint rules in the order they run
+var lintRules = []lintRule{
+	{Name: "control-characters", Description: "file names holding control characters, such as a \\r escape", check: lintControlCharacters},
+	{Name: "missing-images", Description: "images that do not exist on disk", check: lintMissingImages},
+	{Name: "empty-offsets", Description: "offsets whose finish is not after their start", check: lintEmptyOffsets},
+	{Name: "child-larger-than-parent", Description: "configurations larger than their parent", check: lintChildLargerThanParent},
+	{Name: "duplicate-names", Description: "siblings with the same name", check: lintDuplicateNames},
+	{Name: "unused-displays", Description: "displays that no configuration uses", check: lintUnusedDisplays},
+}
+
+// Runs the lint rules over the loaded modules and displays
+type moduleLinter struct {
+	registry *ModuleRegistry
+	displays Displays
+	rule     string
+	findings []LintFinding
+}
+
+func (l *moduleLinter) report(file string, path string, format string, args ...interface{}) {
+	l.findings = append(l.findings, LintFinding{Rule: l.rule, File: file, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Calls fn for every configuration of every module, parents before their children
+func (l *moduleLinter) forEachConfiguration(fn func(config *Configuration)) {
+	var walk func(configs []Configuration)
+	walk = func(configs []Configuration) {
+		for i := range configs {
+			fn(&configs[i])
+			walk(configs[i].Configurations)
+		}
+	}
+	l.registry.ForEach(func(m *Module) bool {
+		walk(m.Configurations)
+		return true
+	})
+}
+
+// Selects the rules from a -lint-rules list, names run only the listed rules and -name disables a rule
+func selectLintRules(list string) ([]lintRule, error) {
+	enabled := map[string]bool{}
+	disabled := map[string]bool{}
+	for _, name := range splitNames(list) {
+		target := enabled
+		if strings.HasPrefix(name, "-") {
+			name, target = name[1:], disabled
+		}
+		if findLintRule(name) == nil {
+			return nil, fmt.Errorf("unknown lint rule %s, the rules are %s", name, strings.Join(lintRuleNames(), ", "))
+		}
+		target[name] = true
+	}
+	var rules []lintRule
+	for _, rule := range lintRules {
+		if disabled[rule.Name] || (len(enabled) > 0 && !enabled[rule.Name]) {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func findLintRule(name string) *lintRule {
+	for i := range lintRules {
+		if lintRules[i].Name == name {
+			return &lintRules[i]
+		}
+	}
+	return nil
+}
+
+func lintRuleNames() []string {
+	names := make([]string, len(lintRules))
+	for i, rule := range lintRules {
+		names[i] = rule.Name
+	}
+	return names
+}
+
+// Runs the rules and returns their findings
+func lintModules(registry *ModuleRegistry, displays Displays, rules []lintRule) []LintFinding {
+	linter := &moduleLinter{registry: registry, displays: displays}
+	for _, rule := range rules {
+		linter.rule = rule.Name
+		rule.check(linter)
+	}
+	return linter.findings
+}
+
+// Writes the findings as text lines or as a JSON array
+func writeLintFindings(w io.Writer, findings []LintFinding, format string) error {
+	switch format {
+	case "json":
+		if findings == nil {
+			findings = []LintFinding{}
+		}
+		data, err := json.MarshalIndent(findings, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "text", "":
+		for _, finding := range findings {
+			fmt.Fprintln(w, finding)
+		}
+		_, err := fmt.Fprintln(w, pluralize(len(findings), "finding", "findings"))
+		return err
+	}
+	return fmt.Errorf("unknown lint format %s, expected text or json", format)
+}
+
+// Returns the index of the first control character of the text, or -1
+func indexControlCharacter(text string) int {
+	return strings.IndexFunc(text, unicode.IsControl)
+}
+
+func lintControlCharacters(l *moduleLinter) {
+	l.registry.ForEach(func(m *Module) bool {
+		if index := indexControlCharacter(m.FileName); index >= 0 {
+			l.report(m.SourceFile, m.Name, "fileName %q holds the control character %q, a backslash may have been read as a JSON escape", m.FileName, m.FileName[index])
+		}
+		return true
+	})
+	l.forEachConfiguration(func(config *Configuration) {
+		if source, ok := config.GetSource("fileName"); ok && source.Layer != SourceFile {
+			// The module file name is reported once for the module
+			return
+		}
+		if index := indexControlCharacter(config.FileName); index >= 0 {
+			l.report(config.GetModuleSourceFile(), config.GetPath(), "fileName %q holds the control character %q, a backslash may have been read as a JSON escape", config.FileName, config.FileName[index])
+		}
+	})
+}
+
+// Returns the path of a resolved file name on this system
+func localPath(fileName string) string {
+	return strings.ReplaceAll(fileName, "\\", string(filepath.Separator))
+}
+
+func lintMissingImages(l *moduleLinter) {
+	reported := map[string]bool{}
+	l.forEachConfiguration(func(config *Configuration) {
+		if len(config.FileName) == 0 || reported[config.FileName] || indexControlCharacter(config.FileName) >= 0 {
+			return
+		}
+		if _, err := os.Stat(localPath(config.FileName)); err != nil {
+			reported[config.FileName] = true
+			l.report(config.GetModuleSourceFile(), config.GetPath(), "image %s does not exist", config.FileName)
+		}
+	})
+}
+
+func lintEmptyOffsets(l *moduleLinter) {
+	l.forEachConfiguration(func(config *Configuration) {
+		check := func(axis string, start int, finish int) {
+			if config.IsSet(axis+"OffsetStart") && config.IsSet(axis+"OffsetFinish") && finish <= start {
+				l.report(config.GetModuleSourceFile(), config.GetPath(), "%sOffsetFinish %d is not after %sOffsetStart %d", axis, finish, axis, start)
+			}
+		}
+		check("x", config.XOffsetStart, config.XOffsetFinish)
+		check("y", config.YOffsetStart, config.YOffsetFinish)
+	})
+}
+
+func lintChildLargerThanParent(l *moduleLinter) {
+	l.forEachConfiguration(func(config *Configuration) {
+		parent := config.Parent
+		if parent == nil {
+			return
+		}
+		if (parent.Width > 0 && config.Width > parent.Width) || (parent.Height > 0 && config.Height > parent.Height) {
+			l.report(config.GetModuleSourceFile(), config.GetPath(), "%dx%d is larger than the %dx%d of its parent", config.Width, config.Height, parent.Width, parent.Height)
+		}
+	})
+}
+
+func lintDuplicateNames(l *moduleLinter) {
+	check := func(configs []Configuration) {
+		seen := map[string]bool{}
+		for i := range configs {
+			if seen[configs[i].Name] {
+				l.report(configs[i].GetModuleSourceFile(), configs[i].GetPath(), "the name %s is used by an earlier sibling", configs[i].Name)
+			}
+			seen[configs[i].Name] = true
+		}
+	}
+	l.registry.ForEach(func(m *Module) bool {
+		check(m.Configurations)
+		return true
+	})
+	l.forEachConfiguration(func(config *Configuration) {
+		check(config.Configurations)
+	})
+}
+
+func lintUnusedDisplays(l *moduleLinter) {
+	used := map[string]bool{}
+	l.forEachConfiguration(func(config *Configuration) {
+		if config.Display != nil {
+			used[config.Display.Name] = true
+		}
+	})
+	for _, display := range l.displays {
+		if !used[display.Name] {
+			l.report("", display.Name, "display %s is not used by any configuration", display.Name)
+		}
+	}
+}
+
+// Lints the loaded modules and writes the findings. Returns false when there are findings or the rules or
+// format are invalid, so -lint can fail a build like -check
+func runLint(w io.Writer, registry *ModuleRegistry, displays Displays, rules string, format string) bool {
+	selected, err := selectLintRules(rules)
+	if err != nil {
+		logger.Log(fmt.Sprintf("Error: %s", err))
+		fmt.Println(err)
+		return false
+	}
+	findings := lintModules(registry, displays, selected)
+	logger.Log(fmt.Sprintf("Lint found %s", pluralize(len(findings), "finding", "findings")))
+	if err := writeLintFindings(w, findings, format); err != nil {
+		logger.Log(fmt.Sprintf("Error: %s", err))
+		fmt.Println(err)
+		return false
+	}
+	return len(findings) == 0
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintModules(t *testing.T) {
+	displays := loadTestDisplays(t)
+	imageDir := t.TempDir()
+	configurationInstance = &MfdConfig{FilePath: imageDir}
+	writeTestImage(t, filepath.Join(imageDir, "Lint.png"), 10, 10)
+	root := writeModuleFiles(t, map[string]string{
+		"Lint.json": `{ "modules": [{ "name": "Lint", "fileName": "Lint.png", "configurations": [
+			{ "name": "LMFD_Lint", "width": 200, "height": 200, "xOffsetStart": 10, "xOffsetFinish": 300, "yOffsetStart": 10, "yOffsetFinish": 300,
+			  "subConfigDef": [
+				{ "name": "Page", "fileName": "Overlays\red.png", "width": 100, "height": 100 },
+				{ "name": "Page", "fileName": "Missing.png", "width": 300, "height": 100, "xOffsetStart": 50, "xOffsetFinish": 50 }
+			] }] }] }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := map[string]int{}
+	for _, finding := range lintModules(registry, displays, lintRules) {
+		count[finding.Rule]++
+	}
+	want := map[string]int{
+		"control-characters":       1,
+		"missing-images":           1,
+		"empty-offsets":            1,
+		"child-larger-than-parent": 1,
+		"duplicate-names":          1,
+		"unused-displays":          len(displays) - 1,
+	}
+	if !reflect.DeepEqual(count, want) {
+		t.Errorf("lintModules() findings per rule = %v, want %v", count, want)
+	}
+
+	rules, err := selectLintRules("-unused-displays,-missing-images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := writeLintFindings(&sb, lintModules(registry, displays, rules), "json"); err != nil {
+		t.Fatal(err)
+	}
+	var findings []LintFinding
+	if err := json.Unmarshal([]byte(sb.String()), &findings); err != nil {
+		t.Fatalf("writeLintFindings() wrote invalid JSON: %v", err)
+	}
+	if len(findings) != 4 || findings[0].Rule != "control-characters" || findings[0].Path != "Lint/LMFD_Lint/Page" {
+		t.Errorf("writeLintFindings() = %+v", findings)
+	}
+
+	// Findings fail the run so -lint can gate a build, a clean run passes
+	sb.Reset()
+	if runLint(&sb, registry, displays, "", "text") || sb.Len() == 0 {
+		t.Errorf("runLint() with findings = true, want false and the findings written")
+	}
+	sb.Reset()
+	if !runLint(&sb, registry, displays, "-control-characters,-missing-images,-empty-offsets,-child-larger-than-parent,-duplicate-names,-unused-displays", "text") {
+		t.Errorf("runLint() without findings = false, want true, wrote %s", sb.String())
+	}
+}
+
+func TestSelectLintRules(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{list: "", want: lintRuleNames()},
+		{list: "duplicate-names, empty-offsets", want: []string{"empty-offsets", "duplicate-names"}},
+		{list: "-unused-displays", want: lintRuleNames()[:5]},
+		{list: "spelling", wantErr: true},
+	}
+	for _, tt := range tests {
+		rules, err := selectLintRules(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("selectLintRules(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+		}
+		var names []string
+		for _, rule := range rules {
+			names = append(names, rule.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("selectLintRules(%q) = %v, want %v", tt.list, names, tt.want)
+		}
+	}
+}
+
+func TestWriteLintFindings(t *testing.T) {
+	findings := []LintFinding{{Rule: "unused-displays", Path: "CDU", Message: "display CDU is not used by any configuration"}}
+	var sb strings.Builder
+	if err := writeLintFindings(&sb, findings, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "CDU: display CDU is not used by any configuration [unused-displays]\n1 finding\n"; sb.String() != want {
+		t.Errorf("writeLintFindings() = %q, want %q", sb.String(), want)
+	}
+	if err := writeLintFindings(os.Stdout, findings, "xml"); err == nil {
+		t.Errorf("writeLintFindings() error = nil, want the unknown format")
+	}
+}
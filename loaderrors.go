@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stores the reason a single module file failed to load
+type ModuleFileError struct {
+	FilePath string
+	Err      error
+}
+
+func (e *ModuleFileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.FilePath, e.Err)
+}
+
+func (e *ModuleFileError) Unwrap() error {
+	return e.Err
+}
+
+// Collects the module files that failed to load while the others were loaded
+type ModuleLoadErrors []*ModuleFileError
+
+func (e ModuleLoadErrors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("%s failed to load", pluralize(len(e), "module file", "module files")))
+	for _, failure := range e {
+		lines = append(lines, "\t"+failure.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e ModuleLoadErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, failure := range e {
+		errs[i] = failure
+	}
+	return errs
+}
+
+// Returns the summary of a module load, such as "Loaded 37 modules, 2 files failed"
+func loadSummary(moduleCount int, failedCount int) string {
+	summary := "Loaded " + pluralize(moduleCount, "module", "modules")
+	if failedCount > 0 {
+		summary += ", " + pluralize(failedCount, "file", "files") + " failed"
+	}
+	return summary
+}
+
+func pluralize(count int, singular string, plural string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, singular)
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
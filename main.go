@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
+	"time"
 )
 
 //var displays Displays
@@ -18,15 +22,28 @@ func loadApplicationConfiguration() {
 		return
 	}
 	configFilePath := filepath.Join(currentUser.HomeDir, "\\Saved Games\\MFDMF\\appsettings.json")
-	LoadConfiguration(configFilePath)
+	config := LoadConfiguration(configFilePath)
+	if len(throttle) > 0 {
+		throttleType, err := ParseThrottleType(throttle)
+		if err != nil {
+			logger.Log(fmt.Sprintf("Error: %s", err))
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		config.ThrottleType = throttleType
+	}
+	logger.Log(fmt.Sprintf("Using %s throttle images", config.GetThrottleType()))
 }
 
-func loadDisplayDefinitions() (Displays, error) {
+func loadDisplayDefinitions(ctx context.Context) (Displays, error) {
 
 	displayJsonPath := configurationInstance.DisplayConfigurationFile
 	displays := Displays{}
-	// Load JSON data.
-	data, err := displays.LoadJSONFile(displayJsonPath)
+	// Load JSON data, a read that outlives the -timeout is abandoned
+	data, err := readFileContext(ctx, displayJsonPath)
+	if ctx.Err() != nil {
+		return displays, &CancelledError{Operation: displayJsonPath, Err: ctx.Err()}
+	}
 	if err != nil {
 		logger.Log(fmt.Sprintf("Error loading JSON file: %v\n", err))
 		os.Exit(1)
@@ -40,13 +57,126 @@ func loadDisplayDefinitions() (Displays, error) {
 	return displays, nil
 }
 
-func loadModuleDefinitions(displays Displays) (Modules, error) {
-	loadPath := configurationInstance.Modules
-	modules, err := readModuleFiles(loadPath, &displays)
+// Loads the module and category files, when the context is done the modules loaded so far are returned
+// with a CancelledError
+func loadModuleDefinitions(ctx context.Context, displays Displays) (*ModuleRegistry, error) {
+	roots := configurationInstance.GetModuleRoots()
+	cache := openModuleCache(getModuleCachePath(), roots, displays, rebuildCache)
+	modules, err := loadModuleRoots(ctx, roots, &displays, cache)
+	var failures ModuleLoadErrors
+	var cancelled *CancelledError
+	if errors.As(err, &cancelled) {
+		// The partial tree is not cached, the next start picks up where this one stopped
+		registry, _ := NewModuleRegistry(modules)
+		return registry, err
+	}
+	if err != nil && !errors.As(err, &failures) {
+		return nil, err
+	}
+	for _, failure := range failures {
+		logger.Log(fmt.Sprintf("Error loading module file %s", failure))
+		fmt.Println(failure)
+	}
+	categories, err := readCategoryRoots(ctx, roots)
+	var categoryFailures ModuleLoadErrors
+	if errors.As(err, &cancelled) {
+		registry, _ := NewModuleRegistry(modules)
+		return registry, err
+	}
+	if err != nil && !errors.As(err, &categoryFailures) {
+		return nil, err
+	}
+	for _, failure := range categoryFailures {
+		logger.Log(fmt.Sprintf("Error loading category file %s", failure))
+		fmt.Println(failure)
+	}
+	failures = append(failures, categoryFailures...)
+	if err := cache.Save(); err != nil {
+		logger.Log(fmt.Sprintf("Error saving the module cache: %s", err))
+	}
+	registry, err := NewModuleRegistry(modules)
 	if err != nil {
 		return nil, err
 	}
-	return modules, nil
+	registry.SetCategories(categories)
+	summary := loadSummary(registry.Len(), len(failures))
+	logger.Log(summary)
+	if len(failures) > 0 {
+		fmt.Println(summary)
+	}
+	return registry, nil
+}
+
+// Reports a load that stopped because the -timeout passed and exits, other errors are left to the caller
+func exitIfCancelled(err error) {
+	var cancelled *CancelledError
+	if errors.As(err, &cancelled) {
+		logger.Log(fmt.Sprintf("Error: %s", err))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// Returns the address of the configuration selected by -mod and -sub, -mod may hold a full address
+func selectedAddress() string {
+	return JoinAddress(module, subModule)
+}
+
+// Prints the selected module or configuration, or every module sorted by category
+func printModules(registry *ModuleRegistry, state *UserState) {
+	if address := selectedAddress(); len(address) > 0 {
+		selected, err := registry.Resolve(address)
+		if err != nil {
+			logger.Log(fmt.Sprintf("Error: %s", err))
+			fmt.Println(err)
+			return
+		}
+		state.Select(selected.Path)
+		saveUserState(state)
+		if selected.Configuration == nil {
+			fmt.Printf("%s\t%s\t%s\t%s\n", selected.Module.Category, selected.Module.Name, selected.Module.DisplayName, selected.Module.Root)
+			return
+		}
+		fmt.Printf("%s\t%s\t%+v\n", selected.Path, selected.Configuration.FileName, *selected.Configuration.GetDimension())
+		return
+	}
+	registry.ForEach(func(m *Module) bool {
+		fmt.Printf("%s\t%s\t%s\n", m.Category, m.Name, m.DisplayName)
+		return true
+	})
+}
+
+// Loads the user state, an unreadable state file is logged and replaced by an empty state
+func loadUserState() *UserState {
+	state, err := LoadUserState(getStateFilePath())
+	if err != nil {
+		logger.Log(fmt.Sprintf("Error loading the user state: %v", err))
+		return &UserState{}
+	}
+	return state
+}
+
+func saveUserState(state *UserState) {
+	if err := state.Save(getStateFilePath()); err != nil {
+		logger.Log(fmt.Sprintf("Error saving the user state: %v", err))
+	}
+}
+
+// Lists or resets the user state, returns true when a state command was run
+func runStateCommands(state *UserState) bool {
+	if resetState {
+		*state = UserState{}
+		saveUserState(state)
+		statusMessage := fmt.Sprintf("The user state has been reset at %s", getStateFilePath())
+		logger.Log(statusMessage)
+		fmt.Println(statusMessage)
+		return true
+	}
+	if listState {
+		fmt.Print(state)
+		return true
+	}
+	return false
 }
 
 func processArguments() {
@@ -62,20 +192,101 @@ func processArguments() {
 	}
 }
 
+// Imports or exports DCS MonitorSetup files, returns true when a MonitorSetup command was run
+func runMonitorSetupCommands(displays Displays) bool {
+	if len(importLua) > 0 {
+		imported, err := importMonitorSetup(importLua, displays)
+		if err != nil {
+			logger.Log(fmt.Sprintf("Error importing MonitorSetup: %v", err))
+			fmt.Println(err)
+			return true
+		}
+		data, err := json.MarshalIndent(imported, "", "\t")
+		if err != nil {
+			logger.Log(fmt.Sprintf("Error marshalling displays: %v", err))
+			return true
+		}
+		fmt.Println(string(data))
+		return true
+	}
+	if len(exportLua) > 0 {
+		if err := exportMonitorSetup(exportLua, displays); err != nil {
+			logger.Log(fmt.Sprintf("Error exporting MonitorSetup: %v", err))
+			fmt.Println(err)
+			return true
+		}
+		statusMessage := fmt.Sprintf("Exported MonitorSetup to %s", exportLua)
+		logger.Log(statusMessage)
+		fmt.Println(statusMessage)
+		return true
+	}
+	return false
+}
+
 var logger = GetLogger()
 
 var (
-	module     string
-	subModule  string
-	verbose    bool
-	clearCache bool
+	module        string
+	subModule     string
+	verbose       bool
+	clearCache    bool
+	importLua     string
+	exportLua     string
+	throttle      string
+	dump          bool
+	explain       string
+	listState     bool
+	setState      string
+	resetState    bool
+	pruneState    bool
+	tree          bool
+	search        string
+	newModule     string
+	newTag        string
+	newImage      string
+	newDisplay    string
+	category      string
+	detect        string
+	preview       string
+	lint          bool
+	lintRulesFlag string
+	lintFormat    string
+	formatMode    bool
+	checkMode     bool
+	rebuildCache  bool
+	timeout       time.Duration
 )
 
 func init() {
-	flag.StringVar(&module, "mod", "", "Module to select")
-	flag.StringVar(&subModule, "sub", "", "Sub-Module to select")
+	flag.StringVar(&module, "mod", "", "Module to select, or a full address such as F-14RHV/LMFD_TomcatRIO/BIT")
+	flag.StringVar(&subModule, "sub", "", "Configuration address within the module, such as LMFD_TomcatRIO/*/BIT_Selected")
 	flag.BoolVar(&verbose, "verbose", false, "Enable verbose mode")
 	flag.BoolVar(&clearCache, "clear", false, "Clears the cache")
+	flag.BoolVar(&rebuildCache, "rebuild-cache", false, "Parses every module file again and rebuilds the module cache")
+	flag.DurationVar(&timeout, "timeout", 0, "Stops loading the displays, modules and images after this long, such as 30s, 0 waits forever")
+	flag.StringVar(&importLua, "import-lua", "", "DCS MonitorSetup Lua file to import as displays")
+	flag.StringVar(&exportLua, "export-lua", "", "DCS MonitorSetup Lua file to generate from the displays")
+	flag.BoolVar(&dump, "dump", false, "Prints the fully resolved tree of the selected modules as JSON")
+	flag.StringVar(&newModule, "new-module", "", "Generates a starter module file with this name")
+	flag.StringVar(&newTag, "tag", "", "Tag of the module generated by -new-module")
+	flag.StringVar(&newImage, "image", "", "Source image of the module generated by -new-module")
+	flag.StringVar(&newDisplay, "displays", "", "Comma separated displays that get a starter configuration, such as LMFD,RMFD")
+	flag.StringVar(&category, "category", "", "Category directory of the module generated by -new-module, such as Jets\\Navy")
+	flag.StringVar(&detect, "detect", "", "Detects the screen regions of a source image and proposes their offsets")
+	flag.StringVar(&preview, "preview", "", "Annotated preview image written by -detect, defaults to the cache folder")
+	flag.BoolVar(&lint, "lint", false, "Checks the modules for common mistakes and exits with status 1 when there are findings")
+	flag.StringVar(&lintRulesFlag, "lint-rules", "", "Comma separated lint rules to run, a rule prefixed with - is disabled")
+	flag.StringVar(&lintFormat, "lint-format", "text", "Output format of -lint, text or json")
+	flag.BoolVar(&formatMode, "fmt", false, "Rewrites the display and module files, or the files named after the flags, in the canonical format")
+	flag.BoolVar(&checkMode, "check", false, "Lists the files that -fmt would change and exits with status 1 when there are any")
+	flag.StringVar(&search, "search", "", "Fuzzy searches the module names, tags, display names and categories")
+	flag.BoolVar(&tree, "tree", false, "Prints the categories, modules and configurations as an indented tree")
+	flag.StringVar(&explain, "explain", "", "Explains which layer supplied a value, such as F-14RHV/LMFD_TomcatRIO/BIT.opacity")
+	flag.BoolVar(&listState, "state", false, "Lists the saved selection, switch states, recents and favorites")
+	flag.StringVar(&setState, "set-state", "", "Updates the saved state, such as switch=F-14RHV/LMFD_TomcatRIO/NAV or favorite=F-14RHV")
+	flag.BoolVar(&resetState, "reset-state", false, "Resets the saved state")
+	flag.BoolVar(&pruneState, "prune-state", false, "Removes the saved switch states, favorites and recents that no longer resolve")
+	flag.StringVar(&throttle, "throttle", "", "HOTAS throttle type (Warthog, Cougar, Virpil, WinWing, X56)")
 }
 
 func main() {
@@ -87,8 +298,29 @@ func main() {
 	// load the configuration
 	loadApplicationConfiguration()
 
+	if formatMode || checkMode {
+		if !runFormat(flag.Args(), checkMode) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	state := loadUserState()
+	if runStateCommands(state) {
+		return
+	}
+
+	// Every load below stops when the -timeout passes
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	// load the display configurations
-	displays, err := loadDisplayDefinitions()
+	displays, err := loadDisplayDefinitions(ctx)
+	exitIfCancelled(err)
 	if err != nil {
 		logger.Log("Unable to load display configuration")
 	} else {
@@ -96,15 +328,110 @@ func main() {
 		logger.Log(fmt.Sprintf("Loaded %d display configurations", displayCount))
 	}
 
-	mods, err := loadModuleDefinitions(displays)
+	if runMonitorSetupCommands(displays) {
+		return
+	}
+
+	if len(detect) > 0 {
+		if err := detectRegions(ctx, detect, preview); err != nil {
+			logger.Log(fmt.Sprintf("Error detecting screen regions: %v", err))
+			fmt.Println(err)
+		}
+		return
+	}
+
+	registry, err := loadModuleDefinitions(ctx, displays)
+	exitIfCancelled(err)
 	if err != nil {
-		logger.Log("Unable to load modules")
-	} else {
-		moduleCount := len(mods)
-		logger.Log(fmt.Sprintf("Loaded %d modules", moduleCount))
+		logger.Log(fmt.Sprintf("Unable to load modules: %v", err))
+		fmt.Println(err)
+		return
+	}
+	state.ApplySwitches(registry)
+
+	if pruneState {
+		removed := state.Prune(registry)
+		saveUserState(state)
+		for _, entry := range removed {
+			fmt.Println(entry)
+		}
+		statusMessage := fmt.Sprintf("Removed %s from the user state", pluralize(len(removed), "entry", "entries"))
+		logger.Log(statusMessage)
+		fmt.Println(statusMessage)
+		return
+	}
+
+	if len(setState) > 0 {
+		if err := state.Set(registry, setState); err != nil {
+			logger.Log(fmt.Sprintf("Error: %s", err))
+			fmt.Println(err)
+			return
+		}
+		saveUserState(state)
+		fmt.Print(state)
+		return
+	}
+
+	if dump {
+		if err := dumpModules(registry, selectedAddress()); err != nil {
+			logger.Log(fmt.Sprintf("Error: %s", err))
+			fmt.Println(err)
+		}
+		return
+	}
+
+	if len(newModule) > 0 {
+		scaffold := ModuleScaffold{
+			Name:        newModule,
+			Tag:         newTag,
+			Category:    category,
+			SourceImage: newImage,
+			Displays:    splitNames(newDisplay),
+		}
+		// New modules are written to the root with the highest precedence
+		root := configurationInstance.Modules
+		if roots := configurationInstance.GetModuleRoots(); len(roots) > 0 {
+			root = roots[0]
+		}
+		filename, err := scaffold.Write(ctx, root, registry, displays)
+		if err != nil {
+			logger.Log(fmt.Sprintf("Error: %s", err))
+			fmt.Println(err)
+			return
+		}
+		statusMessage := fmt.Sprintf("Generated module %s in %s", newModule, filename)
+		logger.Log(statusMessage)
+		fmt.Println(statusMessage)
+		return
+	}
+
+	if lint {
+		if !runLint(os.Stdout, registry, displays, lintRulesFlag, lintFormat) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(search) > 0 {
+		printSearchResults(registry, search)
+		return
+	}
+
+	if tree {
+		printTree(registry, os.Stdout)
+		return
+	}
+
+	if len(explain) > 0 {
+		explanation, err := explainField(registry, explain)
+		if err != nil {
+			logger.Log(fmt.Sprintf("Error: %s", err))
+			fmt.Println(err)
+			return
+		}
+		fmt.Print(explanation)
+		return
 	}
 
-	// Display loaded data.
-	//	fmt.Printf("Display data: %+v\n", displays)
-	fmt.Printf("Modules data: %+v\n", mods)
+	printModules(registry, state)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -31,23 +32,46 @@ type ImageProperties struct {
 
 // Stores a Configuration
 type Configuration struct {
-	Name           string `json:"name"`
-	FileName       string `json:"fileName"`
-	Module         *Module
-	Parent         *Configuration
-	Display        *Display
-	Opacity        float32         `json:"opacity,omitempty"`
-	Center         bool            `json:"center,omitempty"`
-	Enabled        bool            `json:"enabled,omitempty"`
-	Left           int             `json:"left,omitempty"`
-	Top            int             `json:"top,omitempty"`
-	Width          int             `json:"width,omitempty"`
-	Height         int             `json:"height,omitempty"`
-	XOffsetStart   int             `json:"xOffsetStart,omitempty"`
-	XOffsetFinish  int             `json:"xOffsetFinish,omitempty"`
-	YOffsetStart   int             `json:"yOffsetStart,omitempty"`
-	YOffsetFinish  int             `json:"yOffsetFinish,omitempty"`
+	Name          string         `json:"name"`
+	FileName      string         `json:"fileName"`
+	Module        *Module        `json:"-"`
+	Parent        *Configuration `json:"-"`
+	Display       *Display       `json:"-"`
+	Opacity       float32        `json:"opacity,omitempty"`
+	Center        bool           `json:"center,omitempty"`
+	Enabled       bool           `json:"enabled,omitempty"`
+	Left          int            `json:"left,omitempty"`
+	Top           int            `json:"top,omitempty"`
+	Width         int            `json:"width,omitempty"`
+	Height        int            `json:"height,omitempty"`
+	XOffsetStart  int            `json:"xOffsetStart,omitempty"`
+	XOffsetFinish int            `json:"xOffsetFinish,omitempty"`
+	YOffsetStart  int            `json:"yOffsetStart,omitempty"`
+	YOffsetFinish int            `json:"yOffsetFinish,omitempty"`
+	// The fileName is resolved to the image variant of the active throttle type
+	NeedsThrottleType bool `json:"needsThrottleType,omitempty"`
+	// The children are mutually exclusive states, only the active one is enabled
+	UseAsSwitch bool `json:"useAsSwitch,omitempty"`
+	// The name of the child a switch starts on, the first child when empty
+	ActiveChild string `json:"activeChild,omitempty"`
+	// Places the configuration inside its parent, such as top-right, left and top are ignored
+	Anchor         string          `json:"anchor,omitempty"`
+	Margin         *Margins        `json:"margin,omitempty"`
 	Configurations []Configuration `json:"subConfigDef"`
+	// The values set in the module file, see ResolveDefaults
+	explicit *ConfigurationLayer
+	// The layer that supplied each resolved value, keyed by JSON field name
+	provenance map[string]FieldSource
+	// The index of the active child of a switch
+	activeChild int
+	// The enabled value resolved by the defaults cascade, before switches apply
+	cascadeEnabled bool
+	// The path of the switch whose inactive branch holds the configuration
+	disabledBySwitch string
+	// The expressions of the numeric fields set as "=expression", keyed by JSON field name
+	expressions map[string]string
+	// The positions and sizes set as a percentage of the parent rectangle, keyed by JSON field name
+	percentages map[string]float64
 }
 
 // Stores a Module
@@ -58,6 +82,14 @@ type Module struct {
 	FileName       string          `json:"fileName"`
 	Category       string          `json:"category"`
 	Configurations []Configuration `json:"configurations"`
+	// The module file the module was read from
+	SourceFile string `json:"-"`
+	// The module root the module file was found in
+	Root string `json:"-"`
+	// Defaults for every configuration of the module
+	ConfigurationLayer
+	// Changes to the displays for this module only, keyed by display name
+	DisplayOverrides map[string]ConfigurationLayer `json:"displayOverrides,omitempty"`
 }
 
 // Slice of Modules
@@ -101,16 +133,8 @@ func (outer *Configuration) CenterIn(inner *Configuration) (*Rectangle, error) {
 	if !isInside {
 		return nil, errors.New("inner Configuration is bigger than the outer Configuration")
 	}
-	// Calculate the center position of the outer Configuration
-	outerCenterX := outer.Left + (outer.Width / 2)
-	outerCenterY := outer.Top + (outer.Height / 2)
-
-	// Calculate the position to center the inner Configuration
-	innerLeft := outerCenterX - (inner.Width / 2)
-	innerTop := outerCenterY - (inner.Height / 2)
-
-	// Create and return a Rectangle instance
-	return &Rectangle{Left: innerLeft, Top: innerTop, Width: inner.Width, Height: inner.Height}, nil
+	centered := outer.GetDimension().CenterIn(inner.Width, inner.Height)
+	return &centered, nil
 }
 
 func (config *Configuration) GetDisplayRef(displays Displays) (*Display, error) {
@@ -124,6 +148,11 @@ func (config *Configuration) GetDisplayRef(displays Displays) (*Display, error)
 				matched = true
 			}
 			if matched {
+				if module := config.GetModule(); module != nil {
+					if override, ok := module.DisplayOverrides[currentDisplay.Name]; ok {
+						currentDisplay.applyOverride(&override, module.Name)
+					}
+				}
 				return &currentDisplay, nil
 			}
 		}
@@ -131,33 +160,29 @@ func (config *Configuration) GetDisplayRef(displays Displays) (*Display, error)
 	return nil, nil
 }
 
-// SetDefaults for a single Configuration
-func (config *Configuration) SetDefaults(display *Display) {
-	if display != nil {
-		config.Opacity = display.Opacity
-		config.Enabled = display.Enabled
-		config.Center = display.Center
-		config.Left = display.Left
-		config.Top = display.Top
-		config.Width = display.Width
-		config.Height = display.Height
-		config.XOffsetStart = display.XOffsetStart
-		config.YOffsetStart = display.XOffsetFinish
-		config.XOffsetFinish = display.YOffsetStart
-		config.YOffsetFinish = display.YOffsetFinish
-	} else {
-		config.Opacity = 1.0
-		config.Enabled = true
-		config.Center = false
-		config.Left = -1
-		config.Top = -1
-		config.Width = -1
-		config.Height = -1
-		config.XOffsetStart = -1
-		config.YOffsetStart = -1
-		config.XOffsetFinish = -1
-		config.YOffsetFinish = -1
+// Checks that every display override of the module names a display
+func (module *Module) checkDisplayOverrides(displays Displays) error {
+	for name := range module.DisplayOverrides {
+		found := false
+		for i := range displays {
+			if displays[i].Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("display override %s does not name a display", name)
+		}
 	}
+	return nil
+}
+
+// SetDefaults for a single Configuration from the built-in defaults and the display
+func (config *Configuration) SetDefaults(display *Display) {
+	builtin := builtinLayer()
+	fromDisplay := displayLayer(display)
+	config.applyLayer(&builtin)
+	config.applyLayer(&fromDisplay)
 }
 
 // Returns the coordinates that comprise a Configuration area
@@ -185,62 +210,168 @@ func (config *Configuration) GetOffset() (Offsets, error) {
 	return Offsets{XOffsetStart: config.XOffsetStart, XOffsetFinish: config.XOffsetFinish, YOffsetStart: config.YOffsetStart, YOffsetFinish: config.YOffsetFinish}, nil
 }
 
-// Reads all of the modules from the specified path and below
-func readModuleFiles(startingPath string, displays *Displays) (Modules, error) {
+// Reads all of the modules from the specified path and below, files that fail to load are
+// skipped and returned as ModuleLoadErrors together with the modules that did load. When the context is
+// done the walk stops and the modules read so far are returned with a CancelledError
+func readModuleFiles(ctx context.Context, startingPath string, displays *Displays) (Modules, error) {
+	return loadModuleFiles(ctx, startingPath, displays, nil)
+}
+
+// Reads the module files below the starting path, files the cache holds unchanged are not parsed again
+func loadModuleFiles(ctx context.Context, startingPath string, displays *Displays, cache *moduleCache) (Modules, error) {
 	var modules Modules
+	var failures ModuleLoadErrors
+	includes := newIncludeResolver(ctx, startingPath)
 
 	// Walk the directory tree starting from the specified path
-	err := filepath.Walk(startingPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+	err := walkContext(ctx, startingPath, func(filePath string, fileInfo os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			failures = append(failures, &ModuleFileError{FilePath: filePath, Err: err})
+			if fileInfo != nil && fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
-		// Check if the file is a JSON file
-		if filepath.Ext(filePath) == ".json" {
-			// Read the JSON file
-			data, err := os.ReadFile(filePath)
+		// Check if the file is a JSON file, the category.json files are read by readCategoryFiles
+		if !fileInfo.IsDir() && filepath.Ext(filePath) == ".json" && filepath.Base(filePath) != categoryFileName {
+			// The category is the directory of the file relative to the module root, a file below two
+			// roots has a category in each
+			category, err := categoryOf(startingPath, filePath)
 			if err != nil {
-				return err
+				failures = append(failures, &ModuleFileError{FilePath: filePath, Err: err})
+				return nil
+			}
+			if fileModules, ok := cache.lookup(ctx, filePath, displays); ok {
+				for i := range fileModules {
+					fileModules[i].Category = category
+				}
+				modules = append(modules, fileModules...)
+				return nil
+			}
+			fileModules, err := readModuleFile(ctx, filePath, includes, displays)
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-
-			// Unmarshal the JSON data into a wrapper structure with the "Modules" array
-			jsonData := JSONModuleData{}
-			err = json.Unmarshal(data, &jsonData)
 			if err != nil {
-				return err
+				failures = append(failures, &ModuleFileError{FilePath: filePath, Err: err})
+				return nil
 			}
-
-			// Set the Category for each module and process configurations recursively
-			for i := range jsonData.Modules {
-				currentModule := &jsonData.Modules[i]
-				// Calculate the relative Category based on the starting path
-				dir, _ := path.Split(strings.ReplaceAll(filePath, "\\", "/"))
-				relativePath, err := filepath.Rel(getBaseDirectory(), dir)
-				if err != nil {
-					return err
-				}
-				currentModule.Category = relativePath
-				processConfigurationsRecursively(currentModule, nil, currentModule.Configurations, displays)
-				//unmarshalConfigurations(currentModule.Configurations, displays)
+			for i := range fileModules {
+				fileModules[i].Category = category
+			}
+			if file, dependencies, ok := includes.fileStamps(filePath); ok {
+				cache.store(filePath, file, dependencies, fileModules)
 			}
 
-			// Append the modules from the wrapper to the main modules slice
-			modules = append(modules, jsonData.Modules...)
-			return nil
+			// Append the modules from the file to the main modules slice
+			modules = append(modules, fileModules...)
 		}
 
 		return nil
 	})
 
+	if ctx.Err() != nil {
+		return modules, &CancelledError{Operation: "module files below " + startingPath, Progress: pluralize(len(modules), "module", "modules"), Err: ctx.Err()}
+	}
 	if err != nil {
-		return nil, err
+		return modules, err
 	}
+	if len(failures) > 0 {
+		return modules, failures
+	}
+	return modules, nil
+}
 
+// Reads the modules of every root in order of precedence. A module of an earlier root shadows a module of
+// the same name in a later root, two modules of the same name in one root are left to NewModuleRegistry
+func loadModuleRoots(ctx context.Context, roots []string, displays *Displays, cache *moduleCache) (Modules, error) {
+	var modules Modules
+	var failures ModuleLoadErrors
+	rootOf := map[string]string{}
+	for _, root := range roots {
+		rootModules, err := loadModuleFiles(ctx, root, displays, cache)
+		var rootFailures ModuleLoadErrors
+		var cancelled *CancelledError
+		if err != nil && !errors.As(err, &rootFailures) && !errors.As(err, &cancelled) {
+			return modules, err
+		}
+		loaded := 0
+		for i := range rootModules {
+			rootModules[i].Root = root
+			if first, ok := rootOf[rootModules[i].Name]; ok && first != root {
+				logger.Log(fmt.Sprintf("Module %s in %s is shadowed by the module of the same name in %s", rootModules[i].Name, rootModules[i].SourceFile, first))
+				continue
+			}
+			rootOf[rootModules[i].Name] = root
+			modules = append(modules, rootModules[i])
+			loaded++
+		}
+		failures = append(failures, rootFailures...)
+		if cancelled != nil {
+			cancelled.Progress = pluralize(len(modules), "module", "modules")
+			return modules, cancelled
+		}
+		logger.Log(fmt.Sprintf("Loaded %s from %s", pluralize(loaded, "module", "modules"), root))
+	}
+	if len(failures) > 0 {
+		return modules, failures
+	}
 	return modules, nil
 }
 
+// Reads the modules of a single module file
+func readModuleFile(ctx context.Context, filePath string, includes *includeResolver, displays *Displays) (Modules, error) {
+	// Read the JSON file, the stamp of the contents read is kept for the module cache
+	data, stamp, err := readStampedFile(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	includes.stamps[filePath] = stamp
+
+	// Replace the include directives with the fragments they name
+	data, err = includes.ExpandFile(filePath, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instantiate the templates before the configurations are processed
+	data, err = expandTemplates(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the JSON data into a wrapper structure with the "Modules" array
+	jsonData := JSONModuleData{}
+	err = json.Unmarshal(data, &jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Process the configurations of each module recursively
+	for i := range jsonData.Modules {
+		currentModule := &jsonData.Modules[i]
+		currentModule.SourceFile = filePath
+		err = currentModule.checkDisplayOverrides(*displays)
+		if err == nil {
+			err = processConfigurationsRecursively(currentModule, nil, currentModule.Configurations, displays)
+		}
+		if err == nil {
+			err = resolveComputedValues(currentModule)
+		}
+		if err == nil {
+			err = initializeSwitches(currentModule.Configurations)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", currentModule.Name, err)
+		}
+	}
+	return jsonData.Modules, nil
+}
+
 func (currentConfig *Configuration) SetFileName(module *Module) error {
 	if len(currentConfig.FileName) > 0 {
+		currentConfig.recordFileNameSource(FieldSource{Layer: SourceFile, Name: filepathBase(currentConfig.GetModuleSourceFile())})
 		isInCorrectPath, err := path.Match(configurationInstance.FilePath, currentConfig.FileName)
 		if err != nil {
 			return err
@@ -251,6 +382,7 @@ func (currentConfig *Configuration) SetFileName(module *Module) error {
 		}
 	} else {
 		if module != nil && len(module.FileName) > 0 {
+			currentConfig.recordFileNameSource(FieldSource{Layer: SourceModule, Name: module.Name})
 			isInCorrectPath, err := path.Match(configurationInstance.FilePath, module.FileName)
 			if err != nil {
 				return err
@@ -264,7 +396,8 @@ func (currentConfig *Configuration) SetFileName(module *Module) error {
 	return nil
 }
 
-// Recursively process configurations
+// Resolves the decoded configurations in a single pass, each parent before its children, so the defaults
+// and the display of every configuration are applied once
 func processConfigurationsRecursively(module *Module, parent *Configuration, configs []Configuration, displays *Displays) error {
 	for i := range configs {
 		currentConfig := &configs[i]
@@ -276,39 +409,17 @@ func processConfigurationsRecursively(module *Module, parent *Configuration, con
 		}
 		logger.Log(fmt.Sprintf("Getting Display for %s\n", currentConfig.Name))
 		displayRef, _ := currentConfig.GetDisplayRef(*displays)
-		currentConfig.SetDefaults(displayRef)
+		currentConfig.ResolveDefaults(displayRef)
 		currentConfig.Display = displayRef
 		currentConfig.SetFileName(module)
-		err := unmarshalConfigurations(currentConfig.Configurations, displays)
+		err := currentConfig.ResolveThrottleVariant(configurationInstance.GetThrottleType())
 		if err != nil {
 			return err
 		}
-		processConfigurationsRecursively(nil, currentConfig, currentConfig.Configurations, displays)
-	}
-	return nil
-}
-
-// Unmarshal configurations
-func unmarshalConfigurations(configs []Configuration, displays *Displays) error {
-	for i := range configs {
-		currentConfig := &configs[i]
-		configData, err := json.Marshal(currentConfig)
+		err = processConfigurationsRecursively(nil, currentConfig, currentConfig.Configurations, displays)
 		if err != nil {
 			return err
 		}
-		displayRef, err := currentConfig.GetDisplayRef(*displays)
-		if err != nil {
-			return err
-		}
-
-		currentConfig.SetDefaults(displayRef)
-		err = json.Unmarshal(configData, &currentConfig)
-		if err != nil {
-			return err
-		}
-		currentConfig.Display = displayRef
-		currentConfig.SetFileName(nil)
-		unmarshalConfigurations(currentConfig.Configurations, displays)
 	}
 	return nil
 }
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -63,3 +68,76 @@ func TestConfiguration_CenterIn(t *testing.T) {
 type args struct {
 	inner *Configuration
 }
+
+func TestReadModuleFiles_CollectsFailures(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := Displays{}
+	root := writeModuleFiles(t, map[string]string{
+		"A-10C.json":        `{ "modules": [{ "name": "A-10C", "configurations": [{ "name": "LMFD" }] }] }`,
+		"Jets/F-16C.json":   `{ "modules": [{ "name": "F-16C" }, { "name": "F-16CM" }] }`,
+		"Jets/Broken.json":  `{ "modules": [{ "name": "Broken", }] }`,
+		"Jets/Include.json": `{ "modules": [{ "name": "Include", "include": "Missing.json#Fragment" }] }`,
+		"readme.txt":        `not a module file`,
+	})
+
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if len(modules) != 3 {
+		t.Errorf("readModuleFiles() returned %d modules, want 3", len(modules))
+	}
+	var failures ModuleLoadErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("readModuleFiles() error = %v, want ModuleLoadErrors", err)
+	}
+	if len(failures) != 2 {
+		t.Fatalf("readModuleFiles() returned %d failures, want 2", len(failures))
+	}
+	for i, want := range []string{"Broken.json", "Include.json"} {
+		if filepath.Base(failures[i].FilePath) != want {
+			t.Errorf("failure %d is for %s, want %s", i, failures[i].FilePath, want)
+		}
+		if failures[i].Err == nil {
+			t.Errorf("failure %d has no reason", i)
+		}
+	}
+	if got, want := loadSummary(len(modules), len(failures)), "Loaded 3 modules, 2 files failed"; got != want {
+		t.Errorf("loadSummary() = %q, want %q", got, want)
+	}
+}
+
+// Writes a tree of module files in ten categories, each module has a configuration per MFD display with
+// two levels of sub-configurations below it
+func writeSyntheticModules(tb testing.TB, count int) string {
+	tb.Helper()
+	files := map[string]string{}
+	for i := 0; i < count; i++ {
+		var configurations []string
+		for _, display := range []string{"LMFD", "RMFD", "MFD3", "MFD4"} {
+			var pages []string
+			for page := 0; page < 3; page++ {
+				pages = append(pages, fmt.Sprintf(`{ "name": "Page%d", "xOffsetStart": %d, "xOffsetFinish": %d,
+					"subConfigDef": [{ "name": "Page%d_Selected", "left": 135, "top": 635, "width": 100, "height": 30 }, { "name": "Page%d_Alternate", "opacity": 0.5 }] }`,
+					page, page*275, page*275+210, page, page))
+			}
+			configurations = append(configurations, fmt.Sprintf(`{ "name": "%s_Module%d", "subConfigDef": [%s] }`, display, i, strings.Join(pages, ", ")))
+		}
+		files[fmt.Sprintf("Category%d/Module%d.json", i%10, i)] = fmt.Sprintf(`{ "modules": [{ "name": "Module%d", "displayName": "Module %d", "configurations": [%s] }] }`,
+			i, i, strings.Join(configurations, ", "))
+	}
+	return writeModuleFiles(tb, files)
+}
+
+func BenchmarkReadModuleFiles(b *testing.B) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(b)
+	root := writeSyntheticModules(b, 200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		modules, err := readModuleFiles(context.Background(), root, &displays)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(modules) != 200 {
+			b.Fatalf("readModuleFiles() returned %d modules, want 200", len(modules))
+		}
+	}
+}
@@ -0,0 +1,830 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Maps DCS MonitorSetup viewport names to the display names used in displays.json
+var monitorSetupDisplayNames = map[string]string{
+	"Center":      "Main",
+	"LEFT_MFCD":   "LMFD",
+	"RIGHT_MFCD":  "RMFD",
+	"CENTER_MFCD": "CMFD",
+}
+
+// Stores a single named viewport from a DCS MonitorSetup file
+type MonitorViewport struct {
+	Name string
+	Rectangle
+}
+
+// Stores the viewports defined by a DCS MonitorSetup Lua file
+type MonitorSetup struct {
+	Name        string
+	Description string
+	// Camera viewports declared in the Viewports table, such as Center
+	Views []MonitorViewport
+	// Exported viewports declared as globals, such as LEFT_MFCD
+	Exports []MonitorViewport
+}
+
+// Reads and parses a DCS MonitorSetup Lua file
+func LoadMonitorSetup(filename string, screen Rectangle) (*MonitorSetup, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	setup, err := ParseMonitorSetup(data, screen)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return setup, nil
+}
+
+// Parses the Lua subset used by DCS MonitorSetup files, screen supplies the values of screen.width and screen.height
+func ParseMonitorSetup(data []byte, screen Rectangle) (*MonitorSetup, error) {
+	parser := &luaParser{lexer: luaLexer{src: string(data), line: 1}, globals: luaTable{}}
+	screenTable := &luaTable{}
+	screenTable.set("width", float64(screen.Width))
+	screenTable.set("height", float64(screen.Height))
+	if screen.Height != 0 {
+		screenTable.set("aspect", float64(screen.Width)/float64(screen.Height))
+	}
+	parser.globals.set("screen", screenTable)
+	if err := parser.parseChunk(); err != nil {
+		return nil, err
+	}
+
+	setup := &MonitorSetup{}
+	setup.Name, _ = parser.globals.get("name").(string)
+	setup.Description, _ = parser.globals.get("Description").(string)
+
+	views, _ := parser.globals.get("Viewports").(*luaTable)
+	if views != nil {
+		for _, key := range views.keys {
+			view, _ := views.get(key).(*luaTable)
+			if rect, ok := view.rectangle(); ok {
+				setup.Views = append(setup.Views, MonitorViewport{Name: key, Rectangle: rect})
+			}
+		}
+	}
+	for _, key := range parser.globals.keys {
+		table, ok := parser.globals.get(key).(*luaTable)
+		if !ok || key == "screen" || views.contains(table) {
+			continue
+		}
+		if rect, ok := table.rectangle(); ok {
+			setup.Exports = append(setup.Exports, MonitorViewport{Name: key, Rectangle: rect})
+		}
+	}
+	return setup, nil
+}
+
+// Converts the viewports into Display entries, mapping known DCS names to our display names
+func (ms *MonitorSetup) ToDisplays() Displays {
+	displays := Displays{}
+	for _, viewports := range [][]MonitorViewport{ms.Views, ms.Exports} {
+		for _, viewport := range viewports {
+			display := Display{}
+			display.SetDefaults()
+			display.Name = viewport.Name
+			if name, ok := monitorSetupDisplayNames[viewport.Name]; ok {
+				display.Name = name
+			}
+			display.Viewport = viewport.Name
+			display.Left = viewport.Left
+			display.Top = viewport.Top
+			display.Width = viewport.Width
+			display.Height = viewport.Height
+			// A viewport sets every coordinate, so a 0 is written out like any other value
+			left, top, width, height := viewport.Left, viewport.Top, viewport.Width, viewport.Height
+			display.explicit = &ConfigurationLayer{Left: &left, Top: &top, Width: &width, Height: &height}
+			displays = append(displays, display)
+		}
+	}
+	return displays
+}
+
+// Builds a MonitorSetup from the displays, the main display becomes the Center view
+func NewMonitorSetup(name string, displays Displays, mainDisplay string) *MonitorSetup {
+	setup := &MonitorSetup{Name: name, Description: "Generated by load_mfd_config from displays.json"}
+	for _, display := range displays {
+		if display.Name == mainDisplay {
+			setup.Views = append(setup.Views, MonitorViewport{Name: "Center", Rectangle: *display.GetDimension()})
+			continue
+		}
+		viewport := display.GetViewportName()
+		if len(viewport) == 0 {
+			continue
+		}
+		setup.Exports = append(setup.Exports, MonitorViewport{Name: viewport, Rectangle: *display.GetDimension()})
+	}
+	return setup
+}
+
+// Writes the MonitorSetup as a DCS Lua file
+func (ms *MonitorSetup) WriteLua(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("_  = function(p) return p; end;\n")
+	fmt.Fprintf(&buf, "name = _(%s);\n", strconv.Quote(ms.Name))
+	fmt.Fprintf(&buf, "Description = %s\n", strconv.Quote(ms.Description))
+	buf.WriteString("Viewports =\n{\n")
+	for i, view := range ms.Views {
+		fmt.Fprintf(&buf, "\t%s =\n\t{\n", view.Name)
+		writeLuaRectangle(&buf, "\t\t", view.Rectangle)
+		buf.WriteString("\t\tviewDx = 0;\n\t\tviewDy = 0;\n")
+		if view.Height != 0 {
+			fmt.Fprintf(&buf, "\t\taspect = %d / %d;\n", view.Width, view.Height)
+		}
+		buf.WriteString("\t}")
+		if i < len(ms.Views)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+	for _, export := range ms.Exports {
+		fmt.Fprintf(&buf, "\n%s =\n{\n", export.Name)
+		writeLuaRectangle(&buf, "\t", export.Rectangle)
+		buf.WriteString("}\n")
+	}
+	if len(ms.Views) > 0 {
+		fmt.Fprintf(&buf, "\nUIMainView = Viewports.%s\n", ms.Views[0].Name)
+		fmt.Fprintf(&buf, "GU_MAIN_VIEWPORT = Viewports.%s\n", ms.Views[0].Name)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeLuaRectangle(buf *bytes.Buffer, indent string, rect Rectangle) {
+	fmt.Fprintf(buf, "%sx = %d;\n", indent, rect.Left)
+	fmt.Fprintf(buf, "%sy = %d;\n", indent, rect.Top)
+	fmt.Fprintf(buf, "%swidth = %d;\n", indent, rect.Width)
+	fmt.Fprintf(buf, "%sheight = %d;\n", indent, rect.Height)
+}
+
+// Imports the viewports of a MonitorSetup file as displays, using the main display for the screen size
+func importMonitorSetup(filename string, displays Displays) (Displays, error) {
+	screen := Rectangle{}
+	for _, display := range displays {
+		if display.Name == monitorSetupDisplayNames["Center"] {
+			screen = *display.GetDimension()
+		}
+	}
+	setup, err := LoadMonitorSetup(filename, screen)
+	if err != nil {
+		return nil, err
+	}
+	return setup.ToDisplays(), nil
+}
+
+// Writes a MonitorSetup file generated from the displays
+func exportMonitorSetup(filename string, displays Displays) error {
+	name := strings.TrimSuffix(filepathBase(filename), ".lua")
+	setup := NewMonitorSetup(name, displays, monitorSetupDisplayNames["Center"])
+	// The file is replaced in one step so DCS never reads a partly written setup
+	var buffer bytes.Buffer
+	if err := setup.WriteLua(&buffer); err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, buffer.Bytes(), 0644)
+}
+
+// Returns the last element of a path using either separator
+func filepathBase(filename string) string {
+	return filename[strings.LastIndexAny(filename, "\\/")+1:]
+}
+
+// Ordered Lua table, keys keep their declaration order
+type luaTable struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (t *luaTable) set(key string, value interface{}) {
+	if t.values == nil {
+		t.values = map[string]interface{}{}
+	}
+	if _, ok := t.values[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.values[key] = value
+}
+
+func (t *luaTable) get(key string) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.values[key]
+}
+
+// Determines if the table holds the other table as one of its values
+func (t *luaTable) contains(other *luaTable) bool {
+	if t == nil {
+		return false
+	}
+	for _, value := range t.values {
+		if value == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the rectangle described by numeric x, y, width and height fields
+func (t *luaTable) rectangle() (Rectangle, bool) {
+	if t == nil {
+		return Rectangle{}, false
+	}
+	values := make([]int, 4)
+	for i, key := range []string{"x", "y", "width", "height"} {
+		number, ok := t.get(key).(float64)
+		if !ok {
+			return Rectangle{}, false
+		}
+		values[i] = int(number)
+	}
+	return Rectangle{Left: values[0], Top: values[1], Width: values[2], Height: values[3]}, true
+}
+
+type luaTokenKind int
+
+const (
+	luaEOF luaTokenKind = iota
+	luaName
+	luaNumber
+	luaString
+	luaSymbol
+)
+
+type luaToken struct {
+	kind  luaTokenKind
+	text  string
+	value float64
+	line  int
+}
+
+type luaLexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *luaLexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+// Skips whitespace as well as line and block comments
+func (l *luaLexer) skipSpace() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "--[["):
+			end := strings.Index(l.src[l.pos:], "]]")
+			if end < 0 {
+				end = len(l.src) - l.pos
+			} else {
+				end += 2
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+end], "\n")
+			l.pos += end
+		case strings.HasPrefix(l.src[l.pos:], "--"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *luaLexer) next() (luaToken, error) {
+	l.skipSpace()
+	if l.pos >= len(l.src) {
+		return luaToken{kind: luaEOF, line: l.line}, nil
+	}
+	start := l.pos
+	c := rune(l.src[l.pos])
+	switch {
+	case c == '_' || unicode.IsLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || unicode.IsLetter(rune(l.src[l.pos])) || unicode.IsDigit(rune(l.src[l.pos]))) {
+			l.pos++
+		}
+		return luaToken{kind: luaName, text: l.src[start:l.pos], line: l.line}, nil
+	case unicode.IsDigit(c) || (c == '.' && l.pos+1 < len(l.src) && unicode.IsDigit(rune(l.src[l.pos+1]))):
+		l.pos = start + luaNumberLength(l.src[start:])
+		text := l.src[start:l.pos]
+		value, err := parseLuaNumber(text)
+		if err != nil {
+			return luaToken{}, l.errorf("invalid number %q", text)
+		}
+		return luaToken{kind: luaNumber, text: text, value: value, line: l.line}, nil
+	case c == '"' || c == '\'':
+		return l.readString(byte(c))
+	}
+	for _, symbol := range []string{"==", "~=", "<=", ">=", "..", "[[", "="} {
+		if strings.HasPrefix(l.src[l.pos:], symbol) {
+			if symbol == "[[" {
+				return l.readLongString()
+			}
+			l.pos += len(symbol)
+			return luaToken{kind: luaSymbol, text: symbol, line: l.line}, nil
+		}
+	}
+	if strings.ContainsRune("{}[]().,;:+-*/^%#<>", c) {
+		l.pos++
+		return luaToken{kind: luaSymbol, text: string(c), line: l.line}, nil
+	}
+	return luaToken{}, l.errorf("unexpected character %q", c)
+}
+
+// Returns the length of the decimal or hexadecimal number at the start of src
+func luaNumberLength(src string) int {
+	if strings.HasPrefix(src, "0x") || strings.HasPrefix(src, "0X") {
+		n := 2
+		for n < len(src) && strings.ContainsRune("0123456789abcdefABCDEF", rune(src[n])) {
+			n++
+		}
+		return n
+	}
+	n := 0
+	for n < len(src) {
+		c := src[n]
+		switch {
+		case unicode.IsDigit(rune(c)) || c == '.':
+			n++
+		case c == 'e' || c == 'E':
+			n++
+			if n < len(src) && (src[n] == '+' || src[n] == '-') {
+				n++
+			}
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func parseLuaNumber(text string) (float64, error) {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		value, err := strconv.ParseInt(text[2:], 16, 64)
+		return float64(value), err
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+func (l *luaLexer) readString(quote byte) (luaToken, error) {
+	line := l.line
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return luaToken{kind: luaString, text: sb.String(), line: line}, nil
+		case c == '\n':
+			return luaToken{}, l.errorf("unfinished string")
+		case c == '\\' && l.pos+1 < len(l.src):
+			l.pos++
+			switch escaped := l.src[l.pos]; escaped {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			default:
+				sb.WriteByte(escaped)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+		l.pos++
+	}
+	return luaToken{}, l.errorf("unfinished string")
+}
+
+func (l *luaLexer) readLongString() (luaToken, error) {
+	line := l.line
+	end := strings.Index(l.src[l.pos+2:], "]]")
+	if end < 0 {
+		return luaToken{}, l.errorf("unfinished long string")
+	}
+	text := l.src[l.pos+2 : l.pos+2+end]
+	l.line += strings.Count(text, "\n")
+	l.pos += end + 4
+	return luaToken{kind: luaString, text: strings.TrimPrefix(text, "\n"), line: line}, nil
+}
+
+// Recursive descent parser for the assignments, tables and arithmetic used by MonitorSetup files
+type luaParser struct {
+	lexer   luaLexer
+	token   luaToken
+	peeked  bool
+	globals luaTable
+}
+
+func (p *luaParser) peek() (luaToken, error) {
+	if !p.peeked {
+		token, err := p.lexer.next()
+		if err != nil {
+			return luaToken{}, err
+		}
+		p.token = token
+		p.peeked = true
+	}
+	return p.token, nil
+}
+
+func (p *luaParser) advance() (luaToken, error) {
+	token, err := p.peek()
+	p.peeked = false
+	return token, err
+}
+
+// Consumes the next token when it is the given symbol
+func (p *luaParser) accept(symbol string) (bool, error) {
+	token, err := p.peek()
+	if err != nil {
+		return false, err
+	}
+	if token.kind == luaSymbol && token.text == symbol {
+		p.peeked = false
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *luaParser) expect(symbol string) error {
+	ok, err := p.accept(symbol)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("line %d: expected %q but found %q", p.token.line, symbol, p.token.text)
+	}
+	return nil
+}
+
+func (p *luaParser) parseChunk() error {
+	for {
+		token, err := p.peek()
+		if err != nil {
+			return err
+		}
+		if token.kind == luaEOF {
+			return nil
+		}
+		if ok, err := p.accept(";"); err != nil || ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.parseStatement(); err != nil {
+			return err
+		}
+	}
+}
+
+// Parses an assignment to a global or a field of a global table
+func (p *luaParser) parseStatement() error {
+	token, err := p.advance()
+	if err != nil {
+		return err
+	}
+	if token.kind != luaName {
+		return fmt.Errorf("line %d: unexpected %q", token.line, token.text)
+	}
+	if token.text == "local" {
+		if token, err = p.advance(); err != nil {
+			return err
+		}
+	}
+	target := &p.globals
+	key := token.text
+	for {
+		ok, err := p.accept(".")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		field, err := p.advance()
+		if err != nil {
+			return err
+		}
+		next, _ := target.get(key).(*luaTable)
+		if next == nil {
+			next = &luaTable{}
+			target.set(key, next)
+		}
+		target, key = next, field.text
+	}
+	if ok, err := p.accept("("); err != nil || ok {
+		// A bare function call, its result is not needed
+		if err != nil {
+			return err
+		}
+		_, err = p.parseArguments()
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	value, err := p.parseExpression()
+	if err != nil {
+		return err
+	}
+	target.set(key, value)
+	return nil
+}
+
+// Parses +, - and .. with the lowest precedence
+func (p *luaParser) parseExpression() (interface{}, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if token.kind != luaSymbol || (token.text != "+" && token.text != "-" && token.text != "..") {
+			return left, nil
+		}
+		p.advance()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = luaArithmetic(token.text, left, right)
+	}
+}
+
+func (p *luaParser) parseTerm() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if token.kind != luaSymbol || (token.text != "*" && token.text != "/" && token.text != "%") {
+			return left, nil
+		}
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = luaArithmetic(token.text, left, right)
+	}
+}
+
+func (p *luaParser) parseUnary() (interface{}, error) {
+	if ok, err := p.accept("-"); err != nil || ok {
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return luaArithmetic("-", 0.0, value), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *luaParser) parsePrimary() (interface{}, error) {
+	token, err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case token.kind == luaNumber:
+		return token.value, nil
+	case token.kind == luaString:
+		return token.text, nil
+	case token.kind == luaSymbol && token.text == "{":
+		return p.parseTable()
+	case token.kind == luaSymbol && token.text == "(":
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return value, p.expect(")")
+	case token.kind == luaName && token.text == "function":
+		return nil, p.skipFunction()
+	case token.kind == luaName && (token.text == "nil" || token.text == "true" || token.text == "false"):
+		if token.text == "nil" {
+			return nil, nil
+		}
+		return token.text == "true", nil
+	case token.kind == luaName:
+		return p.parseReference(token.text)
+	}
+	return nil, fmt.Errorf("line %d: unexpected %q", token.line, token.text)
+}
+
+// Resolves a dotted name, a call returns its first argument which covers the _('text') idiom
+func (p *luaParser) parseReference(name string) (interface{}, error) {
+	value := p.globals.get(name)
+	for {
+		token, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case token.kind == luaSymbol && token.text == ".":
+			p.advance()
+			field, err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+			table, _ := value.(*luaTable)
+			value = table.get(field.text)
+		case token.kind == luaSymbol && token.text == "(":
+			p.advance()
+			args, err := p.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			value = nil
+			if len(args) > 0 {
+				value = args[0]
+			}
+		default:
+			return value, nil
+		}
+	}
+}
+
+func (p *luaParser) parseArguments() ([]interface{}, error) {
+	var args []interface{}
+	for {
+		if ok, err := p.accept(")"); err != nil || ok {
+			return args, err
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+		if _, err := p.accept(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *luaParser) parseTable() (interface{}, error) {
+	table := &luaTable{}
+	index := 1
+	for {
+		if ok, err := p.accept("}"); err != nil || ok {
+			return table, err
+		}
+		token, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		switch {
+		case token.kind == luaSymbol && token.text == "[":
+			p.advance()
+			keyValue, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			key = fmt.Sprint(keyValue)
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+		case token.kind == luaName:
+			p.advance()
+			next, err := p.peek()
+			if err != nil {
+				return nil, err
+			}
+			if next.kind == luaSymbol && next.text == "=" {
+				p.advance()
+				key = token.text
+			} else {
+				// Not a key, push the name back by re-parsing it as a positional value
+				value, err := p.parseReference(token.text)
+				if err != nil {
+					return nil, err
+				}
+				table.set(strconv.Itoa(index), value)
+				index++
+				if err := p.parseFieldSeparator(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		default:
+			key = strconv.Itoa(index)
+			index++
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		table.set(key, value)
+		if err := p.parseFieldSeparator(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *luaParser) parseFieldSeparator() error {
+	if ok, err := p.accept(","); err != nil || ok {
+		return err
+	}
+	if ok, err := p.accept(";"); err != nil || ok {
+		return err
+	}
+	token, err := p.peek()
+	if err != nil {
+		return err
+	}
+	if token.kind == luaSymbol && token.text == "}" {
+		return nil
+	}
+	return fmt.Errorf("line %d: expected \",\" or \"}\" but found %q", token.line, token.text)
+}
+
+// Skips a function body, MonitorSetup files only declare the _ helper
+func (p *luaParser) skipFunction() error {
+	depth := 1
+	for depth > 0 {
+		token, err := p.advance()
+		if err != nil {
+			return err
+		}
+		if token.kind == luaEOF {
+			return errors.New("unfinished function")
+		}
+		if token.kind != luaName {
+			continue
+		}
+		switch token.text {
+		case "function", "do", "if":
+			depth++
+		case "end":
+			depth--
+		}
+	}
+	return nil
+}
+
+// Applies an arithmetic operator, values that cannot be evaluated become nil
+func luaArithmetic(op string, left, right interface{}) interface{} {
+	if op == ".." {
+		return fmt.Sprint(left) + fmt.Sprint(right)
+	}
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil
+	}
+	switch op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			return nil
+		}
+		return l / r
+	case "%":
+		if r == 0 {
+			return nil
+		}
+		return float64(int(l) % int(r))
+	}
+	return nil
+}
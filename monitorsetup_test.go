@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func loadTestDisplays(t testing.TB) Displays {
+	t.Helper()
+	displays := Displays{}
+	data, err := displays.LoadJSONFile("data/displays.json")
+	if err != nil {
+		t.Fatalf("LoadJSONFile() error = %v", err)
+	}
+	if err := displays.UnmarshalData(data); err != nil {
+		t.Fatalf("UnmarshalData() error = %v", err)
+	}
+	return displays
+}
+
+func TestLoadMonitorSetup(t *testing.T) {
+	screen := Rectangle{Width: 2560, Height: 1440}
+	setup, err := LoadMonitorSetup("data/MonitorSetup/MFCD.lua", screen)
+	if err != nil {
+		t.Fatalf("LoadMonitorSetup() error = %v", err)
+	}
+	if setup.Name != "Camera + LEFT and RIGHT MFCD" {
+		t.Errorf("LoadMonitorSetup() Name = %q", setup.Name)
+	}
+	wantViews := []MonitorViewport{
+		{Name: "Center", Rectangle: Rectangle{Left: 0, Top: 0, Width: 2560, Height: 1440}},
+	}
+	if !reflect.DeepEqual(setup.Views, wantViews) {
+		t.Errorf("LoadMonitorSetup() Views = %v, want %v", setup.Views, wantViews)
+	}
+	wantExports := []MonitorViewport{
+		{Name: "LEFT_MFCD", Rectangle: Rectangle{Left: 2561, Top: 0, Width: 600, Height: 600}},
+		{Name: "RIGHT_MFCD", Rectangle: Rectangle{Left: 3161, Top: 0, Width: 600, Height: 600}},
+		{Name: "CENTER_MFCD", Rectangle: Rectangle{Left: 2561, Top: 601, Width: 600, Height: 600}},
+	}
+	if !reflect.DeepEqual(setup.Exports, wantExports) {
+		t.Errorf("LoadMonitorSetup() Exports = %v, want %v", setup.Exports, wantExports)
+	}
+
+	displays := setup.ToDisplays()
+	wantNames := []string{"Main", "LMFD", "RMFD", "CMFD"}
+	for i, display := range displays {
+		if display.Name != wantNames[i] {
+			t.Errorf("ToDisplays()[%d].Name = %q, want %q", i, display.Name, wantNames[i])
+		}
+	}
+}
+
+func TestMonitorSetup_WriteLua(t *testing.T) {
+	displays := loadTestDisplays(t)
+	setup := NewMonitorSetup("displays", displays, "Main")
+
+	var buf bytes.Buffer
+	if err := setup.WriteLua(&buf); err != nil {
+		t.Fatalf("WriteLua() error = %v", err)
+	}
+	want, err := os.ReadFile("data/MonitorSetup/displays.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("WriteLua() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// The generated file must read back to the same display geometry
+	parsed, err := ParseMonitorSetup(buf.Bytes(), Rectangle{})
+	if err != nil {
+		t.Fatalf("ParseMonitorSetup() error = %v", err)
+	}
+	for _, display := range parsed.ToDisplays() {
+		for _, original := range displays {
+			if original.Name != display.Name {
+				continue
+			}
+			if got, want := *display.GetDimension(), *original.GetDimension(); got != want {
+				t.Errorf("round trip of %s = %v, want %v", display.Name, got, want)
+			}
+		}
+	}
+}
+
+func TestMonitorSetup_NegativeCoordinates(t *testing.T) {
+	displays := Displays{
+		{Name: "Main", Left: -1920, Top: -200, Width: 1920, Height: 1080},
+		{Name: "LMFD", Left: -1920, Top: 880, Width: 600, Height: 600},
+	}
+	var buf bytes.Buffer
+	if err := NewMonitorSetup("displays", displays, "Main").WriteLua(&buf); err != nil {
+		t.Fatalf("WriteLua() error = %v", err)
+	}
+	parsed, err := ParseMonitorSetup(buf.Bytes(), Rectangle{})
+	if err != nil {
+		t.Fatalf("ParseMonitorSetup() error = %v", err)
+	}
+	got := parsed.ToDisplays()
+	if len(got) != len(displays) {
+		t.Fatalf("ToDisplays() = %v, want %d displays", got, len(displays))
+	}
+	for i, display := range got {
+		if *display.GetDimension() != *displays[i].GetDimension() {
+			t.Errorf("round trip of %s = %v, want %v", display.Name, *display.GetDimension(), *displays[i].GetDimension())
+		}
+	}
+}
+
+func TestParseMonitorSetup_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		lua  string
+	}{
+		{name: "Unfinished Table", lua: "LEFT_MFCD = { x = 0; y = 0;"},
+		{name: "Unfinished String", lua: "name = 'MFCD"},
+		{name: "Missing Assignment", lua: "LEFT_MFCD { x = 0 }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMonitorSetup([]byte(tt.lua), Rectangle{}); err == nil {
+				t.Errorf("ParseMonitorSetup() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestExportMonitorSetup(t *testing.T) {
+	displays := loadTestDisplays(t)
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "displays.lua")
+	if err := os.WriteFile(filename, []byte("previous setup"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportMonitorSetup(filename, displays); err != nil {
+		t.Fatalf("exportMonitorSetup() error = %v", err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("data/MonitorSetup/displays.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("exportMonitorSetup() wrote\n%s\nwant\n%s", got, want)
+	}
+	// The temporary file is renamed over the setup
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("exportMonitorSetup() left %d files, want 1", len(entries))
+	}
+}
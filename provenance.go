@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// The layers of the defaults cascade that can supply a configuration value
+const (
+	SourceDefault = "default"
+	SourceDisplay = "display"
+	// A value of the display changed by the module, see Module.DisplayOverrides
+	SourceDisplayOverride = "display override"
+	SourceModule          = "module"
+	SourceParent          = "parent"
+	SourceFile            = "file"
+	// Computed by an expression after the cascade, see resolveComputedValues
+	SourceExpression = "expression"
+	// Placed by a percentage or an anchor relative to the parent, see resolveComputedValues
+	SourcePlacement = "placement"
+)
+
+// Identifies the layer that supplied a configuration value
+type FieldSource struct {
+	Layer string
+	// The display, module, parent configuration path or module file of the layer
+	Name string
+}
+
+func (s FieldSource) String() string {
+	if s.Layer == SourceDefault {
+		return "built-in default"
+	}
+	return s.Layer + " " + s.Name
+}
+
+// Records the source of every value the layer supplies
+func (config *Configuration) recordSources(layer *ConfigurationLayer, source FieldSource) {
+	if layer == nil {
+		return
+	}
+	if config.provenance == nil {
+		config.provenance = map[string]FieldSource{}
+	}
+	value := reflect.ValueOf(layer).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if value.Field(i).IsNil() {
+			continue
+		}
+		config.provenance[jsonFieldName(value.Type().Field(i))] = source
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if len(name) == 0 {
+		return field.Name
+	}
+	return name
+}
+
+// Encodes the struct like json.Marshal, but writes an omitempty field holding its zero value when isSet reports it,
+// so an explicit 0 or opacity 0 survives
+func marshalSetFields(v any, isSet func(field string) bool) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name := jsonFieldName(field)
+		if strings.Contains(tag, ",omitempty") && value.Field(i).IsZero() && !isSet(name) {
+			continue
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		if buffer.Len() > 1 {
+			buffer.WriteString(",")
+		}
+		buffer.Write(key)
+		buffer.WriteString(":")
+		buffer.Write(data)
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// Returns the layer that supplied the value of the field, named by its JSON key
+func (config *Configuration) GetSource(field string) (FieldSource, bool) {
+	source, ok := config.provenance[field]
+	return source, ok
+}
+
+// Returns the address of the configuration, starting with the module name
+func (config *Configuration) GetPath() string {
+	var names []string
+	for current := config; current != nil; current = current.Parent {
+		names = append([]string{current.Name}, names...)
+	}
+	if module := config.GetModule(); module != nil {
+		names = append([]string{module.Name}, names...)
+	}
+	return strings.Join(names, addressSeparator)
+}
+
+// Returns the JSON key and the resolved value of the field named by its JSON key, ignoring case
+func (config *Configuration) GetFieldValue(field string) (string, interface{}, bool) {
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		kind := structField.Type.Kind()
+		if !structField.IsExported() || kind == reflect.Slice || kind == reflect.Pointer {
+			continue
+		}
+		if name := jsonFieldName(structField); strings.EqualFold(name, field) {
+			return name, value.Field(i).Interface(), true
+		}
+	}
+	return "", nil, false
+}
+
+// Describes where the value of the field came from, following inherited values up to the layer that set them
+func (config *Configuration) Explain(field string) (string, error) {
+	name, value, ok := config.GetFieldValue(field)
+	if !ok {
+		return "", fmt.Errorf("configuration %s has no field %s", config.GetPath(), field)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s.%s = %v\n", config.GetPath(), name, value)
+	if name == "enabled" && len(config.disabledBySwitch) > 0 {
+		fmt.Fprintf(&sb, "  disabled by switch %s\n", config.disabledBySwitch)
+		return sb.String(), nil
+	}
+	for current := config; current != nil; current = current.Parent {
+		source, ok := current.GetSource(name)
+		if !ok {
+			sb.WriteString("  not resolved by the defaults cascade\n")
+			break
+		}
+		if source.Layer != SourceParent {
+			fmt.Fprintf(&sb, "  set by %s\n", source)
+			break
+		}
+		fmt.Fprintf(&sb, "  inherited from %s\n", source)
+	}
+	return sb.String(), nil
+}
+
+// Splits an -explain argument such as F-14RHV/LMFD_TomcatRIO/BIT.opacity into the address and the field
+func parseExplainArgument(argument string) (string, string, error) {
+	dot := strings.LastIndex(argument, ".")
+	if dot <= 0 || dot == len(argument)-1 || strings.Contains(argument[dot:], addressSeparator) {
+		return "", "", fmt.Errorf("%s must be an address followed by .field, such as F-14RHV/LMFD_TomcatRIO.opacity", argument)
+	}
+	return argument[:dot], argument[dot+1:], nil
+}
+
+// Explains the field named by an -explain argument
+func explainField(registry *ModuleRegistry, argument string) (string, error) {
+	address, field, err := parseExplainArgument(argument)
+	if err != nil {
+		return "", err
+	}
+	match, err := registry.Resolve(address)
+	if err != nil {
+		return "", err
+	}
+	if match.Configuration == nil {
+		return "", fmt.Errorf("%s is a module, -explain needs a configuration address", address)
+	}
+	return match.Configuration.Explain(field)
+}
+
+func (config *Configuration) recordFileNameSource(source FieldSource) {
+	if config.provenance == nil {
+		config.provenance = map[string]FieldSource{}
+	}
+	config.provenance["fileName"] = source
+}
+
+// Returns the module file the configuration was read from
+func (config *Configuration) GetModuleSourceFile() string {
+	if module := config.GetModule(); module != nil {
+		return module.SourceFile
+	}
+	return ""
+}
@@ -6,3 +6,13 @@ type Rectangle struct {
 	Width  int `json:"width,omitempty"`
 	Height int `json:"height,omitempty"`
 }
+
+// Returns a rectangle of the size centered in the outer rectangle
+func (outer Rectangle) CenterIn(width int, height int) Rectangle {
+	return Rectangle{
+		Left:   outer.Left + (outer.Width / 2) - (width / 2),
+		Top:    outer.Top + (outer.Height / 2) - (height / 2),
+		Width:  width,
+		Height: height,
+	}
+}
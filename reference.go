@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Marks a numeric field whose value is an expression, such as "top": "=../BIT_Selected.top - 30"
+const expressionPrefix = "="
+
+// The JSON names of the numeric fields of a configuration, the fields that may hold an expression
+var expressionFields = numericFieldNames()
+
+func numericFieldNames() map[string]bool {
+	names := map[string]bool{}
+	configType := reflect.TypeOf(Configuration{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Float32, reflect.Float64:
+			if field.IsExported() {
+				names[jsonFieldName(field)] = true
+			}
+		}
+	}
+	return names
+}
+
+// Removes the expressions and percentages of the numeric fields from the decoded fields of the configuration and
+// keeps them on the configuration, they are computed once the defaults are resolved
+func (config *Configuration) extractComputedValues(fields map[string]json.RawMessage) {
+	for key, raw := range fields {
+		if !expressionFields[key] || len(raw) == 0 || raw[0] != '"' {
+			continue
+		}
+		var text string
+		if json.Unmarshal(raw, &text) != nil {
+			continue
+		}
+		if strings.HasPrefix(text, expressionPrefix) {
+			if config.expressions == nil {
+				config.expressions = map[string]string{}
+			}
+			config.expressions[key] = strings.TrimSpace(strings.TrimPrefix(text, expressionPrefix))
+		} else if percent, ok := parsePercent(text); ok && percentFields[key] {
+			if config.percentages == nil {
+				config.percentages = map[string]float64{}
+			}
+			config.percentages[key] = percent
+		} else {
+			continue
+		}
+		delete(fields, key)
+	}
+}
+
+// Identifies a field of a configuration while expressions are evaluated
+type fieldReference struct {
+	config *Configuration
+	field  string
+}
+
+func (r fieldReference) String() string {
+	return r.config.GetPath() + "." + r.field
+}
+
+// The pseudo field that stands for the percentages and anchor of a configuration while they are resolved
+const placementField = "placement"
+
+// Evaluates the expressions and placements of a module in dependency order
+type expressionEvaluator struct {
+	module *Module
+	// The fields being evaluated, in order, to report cycles
+	stack  []fieldReference
+	done   map[fieldReference]bool
+	placed map[*Configuration]bool
+}
+
+// Evaluates every expression and resolves every percentage and anchor of the module in one pass, each
+// value is computed once the values it reads are final, so an expression can read an anchored position
+// and an anchor can place a size computed by an expression. The results are passed on to the children
+// that inherit them
+func resolveComputedValues(module *Module) error {
+	evaluator := &expressionEvaluator{module: module, done: map[fieldReference]bool{}, placed: map[*Configuration]bool{}}
+	var evaluate func(configs []Configuration) error
+	evaluate = func(configs []Configuration) error {
+		for i := range configs {
+			currentConfig := &configs[i]
+			if err := evaluator.place(currentConfig); err != nil {
+				return err
+			}
+			for field := range currentConfig.expressions {
+				if _, err := evaluator.value(fieldReference{currentConfig, field}); err != nil {
+					return err
+				}
+			}
+			if err := evaluate(currentConfig.Configurations); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return evaluate(module.Configurations)
+}
+
+// Pushes the reference on the stack, returns an error when it is already being evaluated
+func (e *expressionEvaluator) enter(reference fieldReference) error {
+	for i, active := range e.stack {
+		if active == reference {
+			chain := append(append([]fieldReference{}, e.stack[i:]...), reference)
+			names := make([]string, len(chain))
+			for j, item := range chain {
+				names[j] = item.String()
+			}
+			return fmt.Errorf("expression cycle %s", strings.Join(names, " -> "))
+		}
+	}
+	e.stack = append(e.stack, reference)
+	return nil
+}
+
+// Returns true when the percentages or the anchor of the configuration set the field
+func (config *Configuration) isPlacedField(field string) bool {
+	if _, ok := config.percentages[field]; ok {
+		return true
+	}
+	return len(config.Anchor) > 0 && (field == "left" || field == "top")
+}
+
+// Resolves the percentages and anchor of the configuration once the rectangle of its parent and its own
+// size are final
+func (e *expressionEvaluator) place(config *Configuration) error {
+	if e.placed[config] || (len(config.percentages) == 0 && len(config.Anchor) == 0) {
+		return nil
+	}
+	if err := e.enter(fieldReference{config, placementField}); err != nil {
+		return fmt.Errorf("configuration %s: %w", config.GetPath(), err)
+	}
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+	for _, field := range []string{"left", "top", "width", "height"} {
+		if config.Parent != nil {
+			if _, err := e.value(fieldReference{config.Parent, field}); err != nil {
+				return err
+			}
+		}
+		if (field == "width" || field == "height") && !config.isPlacedField(field) {
+			if _, err := e.value(fieldReference{config, field}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := config.resolvePlacement(); err != nil {
+		return fmt.Errorf("configuration %s: %w", config.GetPath(), err)
+	}
+	e.placed[config] = true
+	return nil
+}
+
+// Returns the resolved value of the field, placing the configuration or evaluating its expression first
+func (e *expressionEvaluator) value(reference fieldReference) (float64, error) {
+	config := reference.config
+	if config.isPlacedField(reference.field) {
+		if err := e.place(config); err != nil {
+			return 0, err
+		}
+		_, value, _ := config.GetFieldValue(reference.field)
+		return reflect.ValueOf(value).Convert(reflect.TypeOf(float64(0))).Float(), nil
+	}
+	if expression, ok := config.expressions[reference.field]; ok && !e.done[reference] {
+		if err := e.enter(reference); err != nil {
+			return 0, err
+		}
+		result, err := evaluateReferenceExpression(expression, func(name string) (float64, error) {
+			target, err := e.resolveReference(config, name)
+			if err != nil {
+				return 0, err
+			}
+			return e.value(target)
+		})
+		e.stack = e.stack[:len(e.stack)-1]
+		if err != nil {
+			return 0, fmt.Errorf("configuration %s: %s: %w", config.GetPath(), reference.field, err)
+		}
+		config.setNumericField(reference.field, result)
+		config.recordExpressionSource(reference.field, expression)
+		e.done[reference] = true
+		return result, nil
+	}
+	// An inherited value is only final once the parent's expression is evaluated
+	if source, ok := config.GetSource(reference.field); ok && source.Layer == SourceParent && config.Parent != nil {
+		return e.value(fieldReference{config.Parent, reference.field})
+	}
+	_, value, _ := config.GetFieldValue(reference.field)
+	return reflect.ValueOf(value).Convert(reflect.TypeOf(float64(0))).Float(), nil
+}
+
+// Resolves a reference such as ../BIT_Selected.top relative to the configuration. The part after the
+// last dot is the field, the path before it names the configuration: .. is the parent, a name is a
+// child and a leading / starts at the top of the module. A bare field names a field of the configuration
+func (e *expressionEvaluator) resolveReference(config *Configuration, name string) (fieldReference, error) {
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		return e.checkField(fieldReference{config, name}, name)
+	}
+	path, field := name[:dot], name[dot+1:]
+	// A nil configuration stands for the top of the module
+	current := config
+	if strings.HasPrefix(path, addressSeparator) {
+		current, path = nil, strings.TrimPrefix(path, addressSeparator)
+	}
+	for _, segment := range strings.Split(path, addressSeparator) {
+		switch segment {
+		case "", ".":
+		case "..":
+			if current == nil {
+				return fieldReference{}, fmt.Errorf("reference %s leaves the module", name)
+			}
+			current = current.Parent
+		default:
+			children := e.module.Configurations
+			if current != nil {
+				children = current.Configurations
+			}
+			var child *Configuration
+			for i := range children {
+				if children[i].Name == segment {
+					child = &children[i]
+					break
+				}
+			}
+			if child == nil {
+				return fieldReference{}, fmt.Errorf("reference %s: configuration %s was not found", name, segment)
+			}
+			current = child
+		}
+	}
+	if current == nil {
+		return fieldReference{}, fmt.Errorf("reference %s names the module, not a configuration", name)
+	}
+	return e.checkField(fieldReference{current, field}, name)
+}
+
+func (e *expressionEvaluator) checkField(reference fieldReference, name string) (fieldReference, error) {
+	if !expressionFields[reference.field] {
+		return fieldReference{}, fmt.Errorf("reference %s: %s is not a numeric field", name, reference.field)
+	}
+	return reference, nil
+}
+
+// Sets the numeric field named by its JSON key and passes the value on to the children that inherit it
+func (config *Configuration) setNumericField(field string, value float64) {
+	target := reflect.ValueOf(config).Elem()
+	for i := 0; i < target.NumField(); i++ {
+		if jsonFieldName(target.Type().Field(i)) != field {
+			continue
+		}
+		switch target.Field(i).Kind() {
+		case reflect.Int:
+			target.Field(i).SetInt(int64(math.Round(value)))
+		case reflect.Float32, reflect.Float64:
+			target.Field(i).SetFloat(value)
+		}
+	}
+	for i := range config.Configurations {
+		child := &config.Configurations[i]
+		if source, ok := child.GetSource(field); ok && source.Layer == SourceParent {
+			if _, ok := child.expressions[field]; !ok {
+				child.setNumericField(field, value)
+			}
+		}
+	}
+}
+
+func (config *Configuration) recordExpressionSource(field string, expression string) {
+	if config.provenance == nil {
+		config.provenance = map[string]FieldSource{}
+	}
+	config.provenance[field] = FieldSource{Layer: SourceExpression, Name: expressionPrefix + expression}
+}
+
+// Marks the fields an expression, a percentage or the anchor computes as set before they are computed, so
+// the children inherit them like literal values and receive the results from setNumericField
+func (config *Configuration) recordComputedSources() {
+	for field, expression := range config.expressions {
+		config.recordExpressionSource(field, expression)
+	}
+	for field, percent := range config.percentages {
+		config.recordPlacementSource(field, strconv.FormatFloat(percent, 'f', -1, 64)+percentSuffix)
+	}
+	if len(config.Anchor) > 0 {
+		for _, field := range []string{"left", "top"} {
+			config.recordPlacementSource(field, "anchor "+config.Anchor)
+		}
+	}
+}
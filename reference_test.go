@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateExpressions(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"Expressions.json": `{ "modules": [{ "name": "Expressions", "configurations": [
+			{ "name": "LMFD_Pages", "left": 100, "top": 10, "subConfigDef": [
+				{ "name": "SPL_Selected", "top": "=../BIT_Selected.top - 30", "width": "=../.left * 2" },
+				{ "name": "BIT_Selected", "top": 635, "width": 100, "height": "=width / 4", "opacity": "=0.25 + 0.5" },
+				{ "name": "F-18WH", "top": 400, "width": 650 },
+				{ "name": "Hornet", "top": "=../F-18WH.top-30", "width": "=../F-18WH.width/2", "height": "=/LMFD_Pages/F-18WH.top/4+width-325" }
+			] },
+			{ "name": "RMFD_Pages", "left": "=/LMFD_Pages.left + 802", "xOffsetStart": "=/LMFD_Pages/SPL_Selected.width - 100",
+			  "subConfigDef": [{ "name": "Child" }] },
+			{ "name": "Panel", "width": "=100 + 50", "height": "=20 * 2", "subConfigDef": [
+				{ "name": "Inner", "height": "50%", "subConfigDef": [{ "name": "Leaf" }] }
+			] }
+		] }] }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address string
+		field   string
+		want    interface{}
+	}{
+		{address: "Expressions/LMFD_Pages/SPL_Selected", field: "top", want: 605},
+		{address: "Expressions/LMFD_Pages/SPL_Selected", field: "width", want: 200},
+		{address: "Expressions/LMFD_Pages/BIT_Selected", field: "height", want: 25},
+		{address: "Expressions/LMFD_Pages/BIT_Selected", field: "opacity", want: float32(0.75)},
+		// Configuration names may hold dashes and the operators need no spaces
+		{address: "Expressions/LMFD_Pages/Hornet", field: "top", want: 370},
+		{address: "Expressions/LMFD_Pages/Hornet", field: "width", want: 325},
+		{address: "Expressions/LMFD_Pages/Hornet", field: "height", want: 100},
+		{address: "Expressions/RMFD_Pages", field: "left", want: 902},
+		{address: "Expressions/RMFD_Pages", field: "xOffsetStart", want: 100},
+		// The child inherits the computed value of its parent
+		{address: "Expressions/RMFD_Pages/Child", field: "left", want: 902},
+		// A value set only by an expression or a percentage is inherited like a literal one
+		{address: "Expressions/Panel/Inner", field: "width", want: 150},
+		{address: "Expressions/Panel/Inner/Leaf", field: "width", want: 150},
+		{address: "Expressions/Panel/Inner/Leaf", field: "height", want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address+"."+tt.field, func(t *testing.T) {
+			match, err := registry.Resolve(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, got, _ := match.Configuration.GetFieldValue(tt.field); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.field, got, tt.want)
+			}
+		})
+	}
+
+	match, _ := registry.Resolve("Expressions/LMFD_Pages/SPL_Selected")
+	explanation, err := match.Configuration.Explain("top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(explanation, "set by expression =../BIT_Selected.top - 30") {
+		t.Errorf("Explain() = %v, want the expression", explanation)
+	}
+}
+
+func TestEvaluateExpressions_Errors(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	tests := []struct {
+		name   string
+		module string
+		want   string
+	}{
+		{
+			name:   "cycle",
+			module: `{ "name": "A", "top": "=../B.top" }, { "name": "B", "top": "=../C.height + 1" }, { "name": "C", "height": "=../A.top" }`,
+			want:   "expression cycle Test/A.top -> Test/B.top -> Test/C.height -> Test/A.top",
+		},
+		{
+			name:   "self cycle",
+			module: `{ "name": "A", "width": "=height", "height": "=width" }`,
+			want:   "expression cycle",
+		},
+		{
+			name:   "missing configuration",
+			module: `{ "name": "A", "top": "=../Missing.top" }`,
+			want:   "configuration Missing was not found",
+		},
+		{
+			name:   "not numeric",
+			module: `{ "name": "A", "top": "=../A.name" }`,
+			want:   "name is not a numeric field",
+		},
+		{
+			name:   "outside the module",
+			module: `{ "name": "A", "top": "=../../A.top" }`,
+			want:   "leaves the module",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeModuleFiles(t, map[string]string{
+				"Test.json": `{ "modules": [{ "name": "Test", "configurations": [` + tt.module + `] }] }`,
+			})
+			_, err := readModuleFiles(context.Background(), root, &displays)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("readModuleFiles() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanReference(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "../F-18WH.top - 30", want: "../F-18WH.top"},
+		{src: "../F-18WH.top-30", want: "../F-18WH.top"},
+		{src: "../A.width/2", want: "../A.width"},
+		{src: "/LMFD_Pages/F-18WH.top*2", want: "/LMFD_Pages/F-18WH.top"},
+		{src: "../.left*2", want: "../.left"},
+		{src: "width-30", want: "width"},
+		{src: "width/4", want: "width"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := tt.src[:scanReference(tt.src, 0)]; got != tt.want {
+				t.Errorf("scanReference() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Finds the screens of a cockpit export image from the contours of their dark bezels. The edges, where the
+// luminance steps between neighbouring pixels, split the image into regions, and a region is a screen when
+// it is large, roughly rectangular and framed by a dark bezel. The screen itself may be dark with bright
+// symbols or bright, only the frame around it has to be dark
+type RegionDetector struct {
+	// Pixels darker than this luminance belong to a bezel
+	DarkThreshold uint8
+	// Neighbouring pixels whose luminance differs by at least this lie on an edge
+	EdgeThreshold int
+	// The smallest width and height of a screen
+	MinSize int
+	// The smallest share of the box a screen must fill
+	MinFill float64
+	// The smallest share of bezel pixels in the ring around the box
+	MinBezel float64
+	// How far a side of the box may move to reach the strongest edge
+	EdgeMargin int
+}
+
+// The width of the ring around a box that must be bezel
+const bezelRingWidth = 3
+
+func NewRegionDetector() *RegionDetector {
+	return &RegionDetector{DarkThreshold: 60, EdgeThreshold: 16, MinSize: 40, MinFill: 0.5, MinBezel: 0.7, EdgeMargin: 3}
+}
+
+// Stores the luminance of every pixel of an image
+type luminanceImage struct {
+	width  int
+	height int
+	pixels []uint8
+}
+
+// Converts the image row by row, stops with the context error when the context is done
+func newLuminanceImage(ctx context.Context, img image.Image) (*luminanceImage, error) {
+	bounds := img.Bounds()
+	lum := &luminanceImage{width: bounds.Dx(), height: bounds.Dy(), pixels: make([]uint8, bounds.Dx()*bounds.Dy())}
+	for y := 0; y < lum.height; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := 0; x < lum.width; x++ {
+			gray := color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+			lum.pixels[y*lum.width+x] = gray.Y
+		}
+	}
+	return lum, nil
+}
+
+func (lum *luminanceImage) at(x int, y int) int {
+	return int(lum.pixels[y*lum.width+x])
+}
+
+// Returns the candidate screens in reading order, top to bottom and then left to right. The context is checked
+// for every row and every region, a done context stops the detection with its error
+func (d *RegionDetector) Detect(ctx context.Context, img image.Image) ([]image.Rectangle, error) {
+	lum, err := newLuminanceImage(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	dark := make([]bool, len(lum.pixels))
+	for i, value := range lum.pixels {
+		dark[i] = value < d.DarkThreshold
+	}
+	edges, err := d.findEdges(ctx, lum)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []image.Rectangle
+	visited := make([]bool, len(lum.pixels))
+	for start := range lum.pixels {
+		if edges[start] || visited[start] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		box, count := floodFill(lum.width, lum.height, start, edges, visited)
+		if box.Dx() < d.MinSize || box.Dy() < d.MinSize {
+			continue
+		}
+		if snapped := d.snapToEdges(box, lum); d.isScreen(snapped, count, lum, dark) {
+			regions = append(regions, snapped)
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].Min.Y != regions[j].Min.Y {
+			return regions[i].Min.Y < regions[j].Min.Y
+		}
+		return regions[i].Min.X < regions[j].Min.X
+	})
+	return regions, nil
+}
+
+// Marks the pixels on both sides of every luminance step of at least the edge threshold, the marked
+// pixels form the contours that enclose the regions
+func (d *RegionDetector) findEdges(ctx context.Context, lum *luminanceImage) ([]bool, error) {
+	edges := make([]bool, len(lum.pixels))
+	for y := 0; y < lum.height; y++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for x := 0; x < lum.width; x++ {
+			i := y*lum.width + x
+			if x+1 < lum.width && abs(lum.at(x+1, y)-lum.at(x, y)) >= d.EdgeThreshold {
+				edges[i], edges[i+1] = true, true
+			}
+			if y+1 < lum.height && abs(lum.at(x, y+1)-lum.at(x, y)) >= d.EdgeThreshold {
+				edges[i], edges[i+lum.width] = true, true
+			}
+		}
+	}
+	return edges, nil
+}
+
+// Marks the 4-connected pixels inside the contours that are reachable from start, returns their bounding
+// box and count
+func floodFill(width int, height int, start int, edges []bool, visited []bool) (image.Rectangle, int) {
+	box := image.Rect(start%width, start/width, start%width+1, start/width+1)
+	count := 0
+	stack := []int{start}
+	visited[start] = true
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		count++
+		x, y := current%width, current/width
+		box = box.Union(image.Rect(x, y, x+1, y+1))
+		for _, neighbour := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+			nx, ny := neighbour[0], neighbour[1]
+			if nx < 0 || ny < 0 || nx >= width || ny >= height {
+				continue
+			}
+			next := ny*width + nx
+			if !edges[next] && !visited[next] {
+				visited[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+	return box, count
+}
+
+// Determines if the region inside the contour is large, rectangular and framed by a dark bezel
+func (d *RegionDetector) isScreen(box image.Rectangle, count int, lum *luminanceImage, dark []bool) bool {
+	if box.Dx() < d.MinSize || box.Dy() < d.MinSize {
+		return false
+	}
+	area := box.Dx() * box.Dy()
+	if area*10 > lum.width*lum.height*9 || float64(count)/float64(area) < d.MinFill {
+		return false
+	}
+	ring := box.Inset(-bezelRingWidth).Intersect(image.Rect(0, 0, lum.width, lum.height))
+	bezel, total := 0, 0
+	for y := ring.Min.Y; y < ring.Max.Y; y++ {
+		for x := ring.Min.X; x < ring.Max.X; x++ {
+			if (image.Point{X: x, Y: y}).In(box) {
+				continue
+			}
+			total++
+			if dark[y*lum.width+x] {
+				bezel++
+			}
+		}
+	}
+	return total > 0 && float64(bezel)/float64(total) >= d.MinBezel
+}
+
+// Moves each side of the box to the strongest luminance edge within the edge margin
+func (d *RegionDetector) snapToEdges(box image.Rectangle, lum *luminanceImage) image.Rectangle {
+	columnEdge := func(x int) int {
+		strength := 0
+		for y := box.Min.Y; y < box.Max.Y; y++ {
+			strength += abs(lum.at(x, y) - lum.at(x-1, y))
+		}
+		return strength
+	}
+	rowEdge := func(y int) int {
+		strength := 0
+		for x := box.Min.X; x < box.Max.X; x++ {
+			strength += abs(lum.at(x, y) - lum.at(x, y-1))
+		}
+		return strength
+	}
+	// An edge at position p lies between pixels p-1 and p, so it must be within 1..size-1
+	strongest := func(position int, size int, edge func(int) int) int {
+		// A side on the border of the image has no edge to snap to
+		if position <= 0 || position >= size {
+			return position
+		}
+		best, bestStrength := position, -1
+		for p := max(1, position-d.EdgeMargin); p <= min(size-1, position+d.EdgeMargin); p++ {
+			if strength := edge(p); strength > bestStrength {
+				best, bestStrength = p, strength
+			}
+		}
+		if bestStrength <= 0 {
+			return position
+		}
+		return best
+	}
+	snapped := image.Rectangle{
+		Min: image.Point{X: strongest(box.Min.X, lum.width, columnEdge), Y: strongest(box.Min.Y, lum.height, rowEdge)},
+		Max: image.Point{X: strongest(box.Max.X, lum.width, columnEdge), Y: strongest(box.Max.Y, lum.height, rowEdge)},
+	}
+	if snapped.Dx() < d.MinSize || snapped.Dy() < d.MinSize {
+		return box
+	}
+	return snapped
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// Returns the detected screens as configurations ready to paste into a module file
+func regionConfigurations(regions []image.Rectangle) []scaffoldConfiguration {
+	configs := make([]scaffoldConfiguration, len(regions))
+	for i, region := range regions {
+		configs[i] = scaffoldConfiguration{
+			Name:          fmt.Sprintf("Screen%d", i+1),
+			Width:         region.Dx(),
+			Height:        region.Dy(),
+			XOffsetStart:  region.Min.X,
+			XOffsetFinish: region.Max.X,
+			YOffsetStart:  region.Min.Y,
+			YOffsetFinish: region.Max.Y,
+		}
+	}
+	return configs
+}
+
+// Returns a copy of the image with the outline of every region drawn on it
+func annotateRegions(img image.Image, regions []image.Rectangle) *image.RGBA {
+	bounds := img.Bounds()
+	annotated := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(annotated, annotated.Bounds(), img, bounds.Min, draw.Src)
+	outline := image.NewUniform(color.RGBA{R: 255, A: 255})
+	const thickness = 2
+	for _, region := range regions {
+		for _, side := range []image.Rectangle{
+			image.Rect(region.Min.X, region.Min.Y, region.Max.X, region.Min.Y+thickness),
+			image.Rect(region.Min.X, region.Max.Y-thickness, region.Max.X, region.Max.Y),
+			image.Rect(region.Min.X, region.Min.Y, region.Min.X+thickness, region.Max.Y),
+			image.Rect(region.Max.X-thickness, region.Min.Y, region.Max.X, region.Max.Y),
+		} {
+			draw.Draw(annotated, side, outline, image.Point{}, draw.Src)
+		}
+	}
+	return annotated
+}
+
+// Returns the default preview file for an analyzed image, such as Cache\Tomcat_regions.png
+func regionPreviewPath(imagePath string) string {
+	base := filepathBase(imagePath)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return filepath.Join(getCacheBaseDirectroy(), base+"_regions.png")
+}
+
+// Detects the screens of the image, prints the proposed configurations and writes the annotated preview
+func detectRegions(ctx context.Context, imagePath string, previewPath string) error {
+	img, err := decodeImageContext(ctx, sourceImagePath(imagePath))
+	if err != nil {
+		return err
+	}
+
+	regions, err := NewRegionDetector().Detect(ctx, img)
+	if err != nil {
+		return &CancelledError{Operation: "screen regions of " + imagePath, Err: err}
+	}
+	data, err := json.MarshalIndent(regionConfigurations(regions), "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+
+	if len(previewPath) == 0 {
+		previewPath = regionPreviewPath(imagePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(previewPath), 0755); err != nil {
+		return err
+	}
+	preview, err := os.Create(previewPath)
+	if err != nil {
+		return err
+	}
+	defer preview.Close()
+	if err := png.Encode(preview, annotateRegions(img, regions)); err != nil {
+		return err
+	}
+	logger.Log(fmt.Sprintf("Detected %d screen regions in %s, the preview is %s", len(regions), imagePath, previewPath))
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Draws a dark cockpit with two screens, a small light and a thin diagonal wire
+func testCockpitImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	fill := func(r image.Rectangle, gray uint8) {
+		draw.Draw(img, r, image.NewUniform(color.Gray{Y: gray}), image.Point{}, draw.Src)
+	}
+	fill(img.Bounds(), 20)
+	fill(image.Rect(30, 40, 150, 160), 180)
+	// Dark symbology inside the screen must not split it
+	fill(image.Rect(90, 60, 92, 100), 10)
+	fill(image.Rect(220, 40, 370, 200), 200)
+	fill(image.Rect(10, 250, 15, 255), 255)
+	for i := 0; i < 80; i++ {
+		img.Set(200+i, 210+i, color.White)
+	}
+	return img
+}
+
+func TestRegionDetector_Detect(t *testing.T) {
+	got, err := NewRegionDetector().Detect(context.Background(), testCockpitImage())
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	want := []image.Rectangle{image.Rect(30, 40, 150, 160), image.Rect(220, 40, 370, 200)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Detect() = %v, want %v", got, want)
+	}
+
+	configs := regionConfigurations(got)
+	wantConfig := scaffoldConfiguration{Name: "Screen2", Width: 150, Height: 160, XOffsetStart: 220, XOffsetFinish: 370, YOffsetStart: 40, YOffsetFinish: 200}
+	if len(configs) != 2 || configs[1] != wantConfig {
+		t.Errorf("regionConfigurations() = %+v, want %+v second", configs, wantConfig)
+	}
+}
+
+// Draws a grey panel with two dark bezels around dark screens that show bright symbols, like an exported MFD
+func testDarkScreenImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 440, 260))
+	fill := func(r image.Rectangle, gray uint8) {
+		draw.Draw(img, r, image.NewUniform(color.Gray{Y: gray}), image.Point{}, draw.Src)
+	}
+	fill(img.Bounds(), 140)
+	for _, screen := range []image.Rectangle{image.Rect(60, 50, 180, 170), image.Rect(260, 60, 380, 200)} {
+		fill(screen.Inset(-20), 25)
+		fill(screen, 8)
+		// A horizon line, a heading box and a label against the edge of the screen
+		fill(image.Rect(screen.Min.X+10, screen.Min.Y+50, screen.Max.X-10, screen.Min.Y+52), 230)
+		fill(image.Rect(screen.Min.X+40, screen.Min.Y+10, screen.Min.X+70, screen.Min.Y+25), 230)
+		fill(image.Rect(screen.Min.X+44, screen.Min.Y+14, screen.Min.X+66, screen.Min.Y+21), 8)
+		fill(image.Rect(screen.Min.X, screen.Min.Y+80, screen.Min.X+12, screen.Min.Y+86), 230)
+	}
+	// A dark switch on the panel without a screen
+	fill(image.Rect(200, 220, 230, 250), 25)
+	return img
+}
+
+func TestRegionDetector_DetectDarkScreens(t *testing.T) {
+	got, err := NewRegionDetector().Detect(context.Background(), testDarkScreenImage())
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	want := []image.Rectangle{image.Rect(60, 50, 180, 170), image.Rect(260, 60, 380, 200)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Detect() = %v, want %v", got, want)
+	}
+}
+
+func TestRegionDetector_DetectCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, err := NewRegionDetector().Detect(ctx, testCockpitImage()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Detect() = %v, %v, want %v", got, err, context.Canceled)
+	}
+}
+
+func TestAnnotateRegions(t *testing.T) {
+	img := testCockpitImage()
+	annotated := annotateRegions(img, []image.Rectangle{image.Rect(30, 40, 150, 160)})
+	red := color.RGBA{R: 255, A: 255}
+	for _, point := range []image.Point{{30, 40}, {149, 159}, {31, 100}, {90, 158}} {
+		if got := annotated.RGBAAt(point.X, point.Y); got != red {
+			t.Errorf("RGBAAt(%v) = %v, want %v", point, got, red)
+		}
+	}
+	if got := annotated.RGBAAt(60, 100); got == red {
+		t.Errorf("RGBAAt(60, 100) = %v, want the image inside the box", got)
+	}
+}
+
+func TestDetectRegions(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	dir := t.TempDir()
+	source := filepath.Join(dir, "Cockpit.png")
+	writeTestImage(t, source, 10, 10)
+	preview := filepath.Join(dir, "preview", "Cockpit_regions.png")
+	if err := detectRegions(context.Background(), source, preview); err != nil {
+		t.Fatalf("detectRegions() error = %v", err)
+	}
+	if _, err := os.Stat(preview); err != nil {
+		t.Errorf("detectRegions() did not write the preview: %v", err)
+	}
+	if err := detectRegions(context.Background(), filepath.Join(dir, "Missing.png"), preview); err == nil {
+		t.Errorf("detectRegions() error = nil, want error")
+	}
+}
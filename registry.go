@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Indexes the loaded Modules for lookups by Name, Tag, DisplayName and Category
+type ModuleRegistry struct {
+	modules       Modules
+	byName        map[string]*Module
+	byTag         map[string][]*Module
+	byDisplayName map[string][]*Module
+	byCategory    map[string][]*Module
+	categories    map[string]*Category
+}
+
+// Builds a registry from the modules, two modules with the same name are an error
+func NewModuleRegistry(modules Modules) (*ModuleRegistry, error) {
+	registry := &ModuleRegistry{
+		modules:       make(Modules, len(modules)),
+		byName:        map[string]*Module{},
+		byTag:         map[string][]*Module{},
+		byDisplayName: map[string][]*Module{},
+		byCategory:    map[string][]*Module{},
+		categories:    map[string]*Category{},
+	}
+	copy(registry.modules, modules)
+	for i := range registry.modules {
+		currentModule := &registry.modules[i]
+		if existing, ok := registry.byName[currentModule.Name]; ok {
+			return nil, fmt.Errorf("module %s is defined in both %s and %s", currentModule.Name, existing.SourceFile, currentModule.SourceFile)
+		}
+		// Point the configurations at the registry copy of their module
+		for j := range currentModule.Configurations {
+			currentModule.Configurations[j].Module = currentModule
+		}
+		registry.byName[currentModule.Name] = currentModule
+		registry.byTag[currentModule.Tag] = append(registry.byTag[currentModule.Tag], currentModule)
+		displayName := strings.ToLower(currentModule.DisplayName)
+		registry.byDisplayName[displayName] = append(registry.byDisplayName[displayName], currentModule)
+		registry.byCategory[currentModule.Category] = append(registry.byCategory[currentModule.Category], currentModule)
+	}
+	for _, categoryModules := range registry.byCategory {
+		sortModulesByName(categoryModules)
+	}
+	return registry, nil
+}
+
+func sortModulesByName(modules []*Module) {
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Name < modules[j].Name
+	})
+}
+
+// Returns the number of modules in the registry
+func (r *ModuleRegistry) Len() int {
+	return len(r.modules)
+}
+
+// Returns the module with the name
+func (r *ModuleRegistry) GetByName(name string) (*Module, bool) {
+	module, ok := r.byName[name]
+	return module, ok
+}
+
+// Returns the modules with the tag
+func (r *ModuleRegistry) GetByTag(tag string) []*Module {
+	return r.byTag[tag]
+}
+
+// Returns the modules with the display name, ignoring case
+func (r *ModuleRegistry) GetByDisplayName(displayName string) []*Module {
+	return r.byDisplayName[strings.ToLower(displayName)]
+}
+
+// Returns the modules in the category sorted by name
+func (r *ModuleRegistry) GetByCategory(category string) []*Module {
+	return r.byCategory[category]
+}
+
+// Sets the metadata read from the category.json files
+func (r *ModuleRegistry) SetCategories(categories map[string]*Category) {
+	r.categories = categories
+}
+
+// Returns the metadata of the category, a category without a category.json is named after its directory
+func (r *ModuleRegistry) GetCategory(path string) *Category {
+	if category, ok := r.categories[path]; ok {
+		return category
+	}
+	return &Category{Path: path, DisplayName: categoryBaseName(path)}
+}
+
+// Returns the categories holding modules, ordered by sort order and display name with parents before their children
+func (r *ModuleRegistry) Categories() []string {
+	categories := make([]string, 0, len(r.byCategory))
+	for category := range r.byCategory {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return r.categoryLess(categories[i], categories[j])
+	})
+	return categories
+}
+
+// Calls fn for every module sorted by category and then by name, stopping when fn returns false
+func (r *ModuleRegistry) ForEach(fn func(module *Module) bool) {
+	for _, category := range r.Categories() {
+		for _, module := range r.byCategory[category] {
+			if !fn(module) {
+				return
+			}
+		}
+	}
+}
+
+// Finds a single module by name, then tag and then display name
+func (r *ModuleRegistry) Find(key string) (*Module, error) {
+	if module, ok := r.GetByName(key); ok {
+		return module, nil
+	}
+	for _, matches := range [][]*Module{r.GetByTag(key), r.GetByDisplayName(key)} {
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			names := make([]string, len(matches))
+			for i, match := range matches {
+				names[i] = match.Name
+			}
+			return nil, fmt.Errorf("module %q is ambiguous, it matches %s", key, strings.Join(names, ", "))
+		}
+	}
+	if suggestions := r.Suggest(key); len(suggestions) > 0 {
+		return nil, fmt.Errorf("module %q was not found, did you mean %s?", key, strings.Join(suggestions, ", "))
+	}
+	return nil, fmt.Errorf("module %q was not found", key)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testModules() Modules {
+	return Modules{
+		{Name: "F-14RHV", Tag: "F-14B", DisplayName: "F-14B Tomcat (RIO) Hi Viz", Category: "Jets\\Navy", SourceFile: "F-14BRIOHV.json"},
+		{Name: "F-14PHV", Tag: "F-14B", DisplayName: "F-14B Tomcat (Pilot) Hi Viz", Category: "Jets\\Navy", SourceFile: "F-14BPilotHV.json"},
+		{Name: "A-10C", Tag: "A-10C", DisplayName: "A-10C Warthog", Category: "Jets\\Air Force", SourceFile: "A-10C.json"},
+		{Name: "UH-1H", Tag: "UH-1H", DisplayName: "UH-1H Huey", Category: "Helicopters", SourceFile: "UH-1H.json"},
+	}
+}
+
+func TestModuleRegistry_Lookups(t *testing.T) {
+	registry, err := NewModuleRegistry(testModules())
+	if err != nil {
+		t.Fatalf("NewModuleRegistry() error = %v", err)
+	}
+	if module, ok := registry.GetByName("A-10C"); !ok || module.DisplayName != "A-10C Warthog" {
+		t.Errorf("GetByName() = %v, %v", module, ok)
+	}
+	if got := len(registry.GetByTag("F-14B")); got != 2 {
+		t.Errorf("GetByTag() returned %d modules, want 2", got)
+	}
+	if got := registry.GetByDisplayName("uh-1h HUEY"); len(got) != 1 || got[0].Name != "UH-1H" {
+		t.Errorf("GetByDisplayName() = %v", got)
+	}
+	if got := registry.GetByCategory("Jets\\Navy"); len(got) != 2 || got[0].Name != "F-14PHV" {
+		t.Errorf("GetByCategory() = %v", got)
+	}
+
+	var names []string
+	registry.ForEach(func(m *Module) bool {
+		names = append(names, m.Name)
+		return true
+	})
+	if got, want := strings.Join(names, ","), "UH-1H,A-10C,F-14PHV,F-14RHV"; got != want {
+		t.Errorf("ForEach() order = %s, want %s", got, want)
+	}
+}
+
+func TestModuleRegistry_Find(t *testing.T) {
+	registry, err := NewModuleRegistry(testModules())
+	if err != nil {
+		t.Fatalf("NewModuleRegistry() error = %v", err)
+	}
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "F-14RHV", want: "F-14RHV"},
+		{key: "UH-1H", want: "UH-1H"},
+		{key: "a-10c warthog", want: "A-10C"},
+		{key: "F-14B", wantErr: true},
+		{key: "F-16C", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := registry.Find(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ModuleRegistry.Find() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.Name != tt.want {
+				t.Errorf("ModuleRegistry.Find() = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewModuleRegistry_DuplicateName(t *testing.T) {
+	modules := append(testModules(), Module{Name: "A-10C", SourceFile: "A-10C II.json"})
+	_, err := NewModuleRegistry(modules)
+	if err == nil {
+		t.Fatal("NewModuleRegistry() error = nil, want duplicate error")
+	}
+	if !strings.Contains(err.Error(), "A-10C.json") || !strings.Contains(err.Error(), "A-10C II.json") {
+		t.Errorf("NewModuleRegistry() error = %v, want both files named", err)
+	}
+}
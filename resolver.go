@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Stores the values a single layer of the defaults cascade supplies, nil fields leave the value to the layers below
+type ConfigurationLayer struct {
+	Opacity           *float32 `json:"opacity,omitempty"`
+	Enabled           *bool    `json:"enabled,omitempty"`
+	Center            *bool    `json:"center,omitempty"`
+	Left              *int     `json:"left,omitempty"`
+	Top               *int     `json:"top,omitempty"`
+	Width             *int     `json:"width,omitempty"`
+	Height            *int     `json:"height,omitempty"`
+	XOffsetStart      *int     `json:"xOffsetStart,omitempty"`
+	XOffsetFinish     *int     `json:"xOffsetFinish,omitempty"`
+	YOffsetStart      *int     `json:"yOffsetStart,omitempty"`
+	YOffsetFinish     *int     `json:"yOffsetFinish,omitempty"`
+	NeedsThrottleType *bool    `json:"needsThrottleType,omitempty"`
+}
+
+// Returns true when the layer supplies the field named by its JSON key
+func (layer *ConfigurationLayer) has(field string) bool {
+	if layer == nil {
+		return false
+	}
+	value := reflect.ValueOf(layer).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if jsonFieldName(value.Type().Field(i)) == field {
+			return !value.Field(i).IsNil()
+		}
+	}
+	return false
+}
+
+// Returns the values used when no layer supplies one
+func builtinLayer() ConfigurationLayer {
+	opacity := float32(1.0)
+	enabled := true
+	center := false
+	needsThrottleType := false
+	// Coordinates no other layer supplies are zero and count as unset, see IsSet
+	unset := 0
+	return ConfigurationLayer{
+		Opacity:           &opacity,
+		Enabled:           &enabled,
+		Center:            &center,
+		Left:              &unset,
+		Top:               &unset,
+		Width:             &unset,
+		Height:            &unset,
+		XOffsetStart:      &unset,
+		XOffsetFinish:     &unset,
+		YOffsetStart:      &unset,
+		YOffsetFinish:     &unset,
+		NeedsThrottleType: &needsThrottleType,
+	}
+}
+
+// Returns the values a display supplies, coordinates the display does not set are left alone
+func displayLayer(display *Display) ConfigurationLayer {
+	if display == nil {
+		return ConfigurationLayer{}
+	}
+	opacity := display.Opacity
+	enabled := display.Enabled
+	center := display.Center
+	needsThrottleType := display.NeedsThrottleType
+	return ConfigurationLayer{
+		Opacity:           &opacity,
+		Enabled:           &enabled,
+		Center:            &center,
+		Left:              display.optional("left", display.Left),
+		Top:               display.optional("top", display.Top),
+		Width:             display.optional("width", display.Width),
+		Height:            display.optional("height", display.Height),
+		XOffsetStart:      display.optional("xOffsetStart", display.XOffsetStart),
+		XOffsetFinish:     display.optional("xOffsetFinish", display.XOffsetFinish),
+		YOffsetStart:      display.optional("yOffsetStart", display.YOffsetStart),
+		YOffsetFinish:     display.optional("yOffsetFinish", display.YOffsetFinish),
+		NeedsThrottleType: &needsThrottleType,
+	}
+}
+
+// Returns the resolved values of a parent configuration, the throttle type and unset coordinates are not inherited
+func parentLayer(parent *Configuration) ConfigurationLayer {
+	if parent == nil {
+		return ConfigurationLayer{}
+	}
+	opacity := parent.Opacity
+	enabled := parent.Enabled
+	center := parent.Center
+	return ConfigurationLayer{
+		Opacity:       &opacity,
+		Enabled:       &enabled,
+		Center:        &center,
+		Left:          parent.optional("left", parent.Left),
+		Top:           parent.optional("top", parent.Top),
+		Width:         parent.optional("width", parent.Width),
+		Height:        parent.optional("height", parent.Height),
+		XOffsetStart:  parent.optional("xOffsetStart", parent.XOffsetStart),
+		XOffsetFinish: parent.optional("xOffsetFinish", parent.XOffsetFinish),
+		YOffsetStart:  parent.optional("yOffsetStart", parent.YOffsetStart),
+		YOffsetFinish: parent.optional("yOffsetFinish", parent.YOffsetFinish),
+	}
+}
+
+// Copies every value the layer supplies into the configuration
+func (config *Configuration) applyLayer(layer *ConfigurationLayer) {
+	if layer == nil {
+		return
+	}
+	if layer.Opacity != nil {
+		config.Opacity = *layer.Opacity
+	}
+	if layer.Enabled != nil {
+		config.Enabled = *layer.Enabled
+	}
+	if layer.Center != nil {
+		config.Center = *layer.Center
+	}
+	if layer.Left != nil {
+		config.Left = *layer.Left
+	}
+	if layer.Top != nil {
+		config.Top = *layer.Top
+	}
+	if layer.Width != nil {
+		config.Width = *layer.Width
+	}
+	if layer.Height != nil {
+		config.Height = *layer.Height
+	}
+	if layer.XOffsetStart != nil {
+		config.XOffsetStart = *layer.XOffsetStart
+	}
+	if layer.XOffsetFinish != nil {
+		config.XOffsetFinish = *layer.XOffsetFinish
+	}
+	if layer.YOffsetStart != nil {
+		config.YOffsetStart = *layer.YOffsetStart
+	}
+	if layer.YOffsetFinish != nil {
+		config.YOffsetFinish = *layer.YOffsetFinish
+	}
+	if layer.NeedsThrottleType != nil {
+		config.NeedsThrottleType = *layer.NeedsThrottleType
+	}
+}
+
+// Returns the module of the configuration, nested configurations use the module of their top level parent
+func (config *Configuration) GetModule() *Module {
+	for current := config; current != nil; current = current.Parent {
+		if current.Module != nil {
+			return current.Module
+		}
+	}
+	return nil
+}
+
+// Returns true when a layer above the built-in defaults supplied the field named by its JSON key, so an
+// explicit 0 or a negative coordinate counts as set
+func (config *Configuration) IsSet(field string) bool {
+	source, ok := config.GetSource(field)
+	return ok && source.Layer != SourceDefault
+}
+
+// Encodes the configuration with every field the module file or the defaults cascade sets, even when it is zero
+func (config Configuration) MarshalJSON() ([]byte, error) {
+	type plainConfiguration Configuration
+	return marshalSetFields(plainConfiguration(config), func(field string) bool {
+		return config.IsSet(field) || config.explicit.has(field)
+	})
+}
+
+// Returns a pointer to the coordinate, or nil when it is not set
+func (config *Configuration) optional(field string, value int) *int {
+	if !config.IsSet(field) {
+		return nil
+	}
+	return &value
+}
+
+// Resolves the values of the configuration from the cascade, each layer overrides the ones before it:
+//  1. built-in defaults, opacity 1, enabled, not centered and unset coordinates of 0
+//  2. the display matched by the configuration name, with the overrides of the module
+//  3. the defaults of the module
+//  4. the resolved values of the parent configuration
+//  5. the values set on the configuration in the module file
+//
+// The parent must be resolved before its children.
+func (config *Configuration) ResolveDefaults(display *Display) {
+	config.provenance = map[string]FieldSource{}
+	builtin := builtinLayer()
+	config.applySource(&builtin, FieldSource{Layer: SourceDefault})
+	if display != nil {
+		fromDisplay := displayLayer(display)
+		config.applySource(&fromDisplay, FieldSource{Layer: SourceDisplay, Name: display.Name})
+		if display.override != nil {
+			config.recordSources(display.override, FieldSource{Layer: SourceDisplayOverride, Name: display.Name + " in module " + display.overriddenBy})
+		}
+	}
+	module := config.GetModule()
+	if module != nil {
+		config.applySource(&module.ConfigurationLayer, FieldSource{Layer: SourceModule, Name: module.Name})
+	}
+	if config.Parent != nil {
+		fromParent := parentLayer(config.Parent)
+		config.applySource(&fromParent, FieldSource{Layer: SourceParent, Name: config.Parent.GetPath()})
+	}
+	fileSource := FieldSource{Layer: SourceFile}
+	if module != nil {
+		fileSource.Name = filepathBase(module.SourceFile)
+	}
+	config.applySource(config.explicit, fileSource)
+	config.recordComputedSources()
+	config.cascadeEnabled = config.Enabled
+}
+
+// Applies the layer and records it as the source of the values it supplies
+func (config *Configuration) applySource(layer *ConfigurationLayer, source FieldSource) {
+	config.applyLayer(layer)
+	config.recordSources(layer, source)
+}
+
+// Decodes a configuration and records the values the module file sets explicitly. Every value is decoded
+// once: the cascade fields into the explicit layer, which then supplies the plain values, and the others into
+// the configuration. The defaults cascade is applied afterwards by ResolveDefaults
+func (config *Configuration) UnmarshalJSON(data []byte) error {
+	type plainConfiguration Configuration
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	config.extractComputedValues(fields)
+	var layer ConfigurationLayer
+	if err := decodeFields(fields, &layer, layerFieldIndexes); err != nil {
+		return err
+	}
+	if err := decodeFields(fields, (*plainConfiguration)(config), configurationFieldIndexes); err != nil {
+		return err
+	}
+	config.applyLayer(&layer)
+	config.explicit = &layer
+	return nil
+}
+
+// The index of every decoded field of the layer and of the configuration, keyed by JSON field name
+var (
+	layerFieldIndexes         = jsonFieldIndexes(reflect.TypeOf(ConfigurationLayer{}))
+	configurationFieldIndexes = jsonFieldIndexes(reflect.TypeOf(Configuration{}))
+)
+
+func jsonFieldIndexes(structType reflect.Type) map[string]int {
+	indexes := map[string]int{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.IsExported() && field.Tag.Get("json") != "-" {
+			indexes[jsonFieldName(field)] = i
+		}
+	}
+	return indexes
+}
+
+// Decodes the values of the fields into the struct the target points to and removes them from the map, so
+// each value is decoded once. Keys match like encoding/json, an exact match first and then ignoring case
+func decodeFields(fields map[string]json.RawMessage, target any, indexes map[string]int) error {
+	value := reflect.ValueOf(target).Elem()
+	for key, raw := range fields {
+		index, ok := indexes[key]
+		if !ok {
+			for name, candidate := range indexes {
+				if strings.EqualFold(name, key) {
+					index, ok = candidate, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, value.Field(index).Addr().Interface()); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		delete(fields, key)
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Loads the F-14 RIO module file with the test displays
+func loadTestModules(t *testing.T) *ModuleRegistry {
+	t.Helper()
+	displays := loadTestDisplays(t)
+	configurationInstance = &MfdConfig{}
+
+	dir := t.TempDir()
+	data, err := os.ReadFile("data/F-14BRIOHV.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "F-14BRIOHV.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	modules, err := readModuleFiles(context.Background(), dir, &displays)
+	if err != nil {
+		t.Fatalf("readModuleFiles() error = %v", err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatalf("NewModuleRegistry() error = %v", err)
+	}
+	return registry
+}
+
+func TestConfiguration_ResolveDefaults(t *testing.T) {
+	registry := loadTestModules(t)
+	tests := []struct {
+		address   string
+		rectangle Rectangle
+		offsets   Offsets
+		opacity   float32
+		center    bool
+	}{
+		{
+			// Everything comes from the LMFD display
+			address:   "F-14RHV/LMFD_TomcatRIO",
+			rectangle: Rectangle{Left: 2561, Top: 0, Width: 600, Height: 600},
+			offsets:   Offsets{XOffsetStart: 101, XOffsetFinish: 776, YOffsetStart: 250, YOffsetFinish: 900},
+			opacity:   0.5,
+		},
+		{
+			// The position and opacity come from the parent, the size and offsets from the file
+			address:   "F-14RHV/LMFD_TomcatRIO/BIT",
+			rectangle: Rectangle{Left: 2561, Top: 0, Width: 210, Height: 469},
+			offsets:   Offsets{XOffsetStart: 50, XOffsetFinish: 260, YOffsetStart: 1, YOffsetFinish: 430},
+			opacity:   0.5,
+			center:    true,
+		},
+		{
+			// Explicit zero offsets survive, center is inherited from BIT
+			address:   "F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected",
+			rectangle: Rectangle{Left: 135, Top: 635, Width: 100, Height: 30},
+			offsets:   Offsets{XOffsetStart: 0, XOffsetFinish: 100, YOffsetStart: 0, YOffsetFinish: 20},
+			opacity:   0.85,
+			center:    true,
+		},
+		{
+			// The file values take precedence over the F-18WH display
+			address:   "F-14RHV/WHKEY_TomcatRIO/F-18WH",
+			rectangle: Rectangle{Left: 550, Top: 100, Width: 650, Height: 800},
+			offsets:   Offsets{XOffsetStart: 1, XOffsetFinish: 1286, YOffsetStart: 1, YOffsetFinish: 1340},
+			opacity:   1.0,
+		},
+		{
+			// Inherited through four levels of parents
+			address:   "F-14RHV/WHKEY_TomcatRIO/F-18WH/**/F-18WH4",
+			rectangle: Rectangle{Left: 550, Top: 100, Width: 650, Height: 800},
+			offsets:   Offsets{XOffsetStart: 1, XOffsetFinish: 1286, YOffsetStart: 1, YOffsetFinish: 1340},
+			opacity:   1.0,
+		},
+		{
+			// The position comes from the RMFD display through the parent
+			address:   "F-14RHV/RMFD_TomcatRIO/Flir",
+			rectangle: Rectangle{Left: 3161, Top: 0, Width: 600, Height: 600},
+			offsets:   Offsets{XOffsetStart: 0, XOffsetFinish: 380, YOffsetStart: 0, YOffsetFinish: 440},
+			opacity:   1.0,
+			center:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			match, err := registry.Resolve(tt.address)
+			if err != nil {
+				t.Fatalf("ModuleRegistry.Resolve() error = %v", err)
+			}
+			config := match.Configuration
+			if got := *config.GetDimension(); got != tt.rectangle {
+				t.Errorf("GetDimension() = %+v, want %+v", got, tt.rectangle)
+			}
+			if got, _ := config.GetOffset(); got != tt.offsets {
+				t.Errorf("GetOffset() = %+v, want %+v", got, tt.offsets)
+			}
+			if config.Opacity != tt.opacity {
+				t.Errorf("Opacity = %v, want %v", config.Opacity, tt.opacity)
+			}
+			if config.Center != tt.center {
+				t.Errorf("Center = %v, want %v", config.Center, tt.center)
+			}
+			if !config.Enabled {
+				t.Errorf("Enabled = false, want true")
+			}
+		})
+	}
+}
+
+func TestConfiguration_ResolveDefaults_Module(t *testing.T) {
+	opacity := float32(0.25)
+	enabled := false
+	module := &Module{Name: "Test", ConfigurationLayer: ConfigurationLayer{Opacity: &opacity, Enabled: &enabled}}
+	display := &Display{}
+	display.SetDefaults()
+	display.Opacity = 0.5
+	display.Width = 600
+
+	config := &Configuration{Name: "LMFD", Module: module}
+	config.ResolveDefaults(display)
+	if config.Opacity != 0.25 || config.Enabled || config.Width != 600 {
+		t.Errorf("ResolveDefaults() = opacity %v enabled %v width %v, want module opacity and display width", config.Opacity, config.Enabled, config.Width)
+	}
+
+	explicitOpacity := float32(0)
+	child := &Configuration{Name: "Child", Parent: config, explicit: &ConfigurationLayer{Opacity: &explicitOpacity}}
+	child.ResolveDefaults(nil)
+	if child.Opacity != 0 || child.Enabled || child.Width != 600 {
+		t.Errorf("ResolveDefaults() = opacity %v enabled %v width %v, want explicit opacity and parent values", child.Opacity, child.Enabled, child.Width)
+	}
+}
+
+func TestConfiguration_ResolveDefaults_NegativeAndZero(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	// A monitor left of the primary has a negative position, an explicit 0 is set too
+	displays := Displays{}
+	if err := displays.UnmarshalData([]byte(`[{ "name": "Left", "left": -1, "top": 0, "width": 800, "height": 600, "xOffsetStart": 0 }]`)); err != nil {
+		t.Fatal(err)
+	}
+	root := writeModuleFiles(t, map[string]string{
+		"Test.json": `{ "modules": [{ "name": "Test", "configurations": [
+			{ "name": "Left", "opacity": 0, "subConfigDef": [
+				{ "name": "Child", "top": -20 },
+				{ "name": "Zero", "left": 0, "enabled": false }
+			] }
+		] }] }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address string
+		field   string
+		want    interface{}
+		wantSet bool
+	}{
+		{address: "Test/Left", field: "left", want: -1, wantSet: true},
+		{address: "Test/Left", field: "top", want: 0, wantSet: true},
+		{address: "Test/Left", field: "xOffsetStart", want: 0, wantSet: true},
+		{address: "Test/Left", field: "xOffsetFinish", want: 0, wantSet: false},
+		{address: "Test/Left", field: "opacity", want: float32(0), wantSet: true},
+		{address: "Test/Left/Child", field: "left", want: -1, wantSet: true},
+		{address: "Test/Left/Child", field: "top", want: -20, wantSet: true},
+		{address: "Test/Left/Child", field: "opacity", want: float32(0), wantSet: true},
+		{address: "Test/Left/Zero", field: "left", want: 0, wantSet: true},
+		{address: "Test/Left/Zero", field: "xOffsetFinish", want: 0, wantSet: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address+"."+tt.field, func(t *testing.T) {
+			match, err := registry.Resolve(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, got, _ := match.Configuration.GetFieldValue(tt.field); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.field, got, tt.want)
+			}
+			if got := match.Configuration.IsSet(tt.field); got != tt.wantSet {
+				t.Errorf("IsSet(%s) = %v, want %v", tt.field, got, tt.wantSet)
+			}
+		})
+	}
+}
+
+func TestConfiguration_ResolveDefaults_DisplayOverrides(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"Tall.json": `{ "modules": [{ "name": "Tall",
+			"displayOverrides": { "LMFD": { "height": 620, "opacity": 0, "top": -20 } },
+			"configurations": [{ "name": "LMFD_Tall", "subConfigDef": [{ "name": "Child" }] }] }] }`,
+		"Plain.json": `{ "modules": [{ "name": "Plain", "configurations": [{ "name": "LMFD_Plain" }] }] }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address   string
+		rectangle Rectangle
+		opacity   float32
+	}{
+		{address: "Tall/LMFD_Tall", rectangle: Rectangle{Left: 2561, Top: -20, Width: 600, Height: 620}, opacity: 0},
+		{address: "Tall/LMFD_Tall/Child", rectangle: Rectangle{Left: 2561, Top: -20, Width: 600, Height: 620}, opacity: 0},
+		// The shared display is left alone for other modules
+		{address: "Plain/LMFD_Plain", rectangle: Rectangle{Left: 2561, Top: 0, Width: 600, Height: 600}, opacity: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			match, err := registry.Resolve(tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := *match.Configuration.GetDimension(); got != tt.rectangle {
+				t.Errorf("GetDimension() = %v, want %v", got, tt.rectangle)
+			}
+			if got := match.Configuration.Opacity; got != tt.opacity {
+				t.Errorf("Opacity = %v, want %v", got, tt.opacity)
+			}
+		})
+	}
+
+	match, _ := registry.Resolve("Tall/LMFD_Tall")
+	explanation, err := match.Configuration.Explain("height")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(explanation, "set by display override LMFD in module Tall") {
+		t.Errorf("Explain() = %v, want the display override", explanation)
+	}
+	explanation, _ = match.Configuration.Explain("width")
+	if !strings.Contains(explanation, "set by display LMFD") {
+		t.Errorf("Explain() = %v, want the display", explanation)
+	}
+
+	root = writeModuleFiles(t, map[string]string{
+		"Unknown.json": `{ "modules": [{ "name": "Unknown", "displayOverrides": { "LMDF": { "height": 620 } } }] }`,
+	})
+	if _, err := readModuleFiles(context.Background(), root, &displays); err == nil || !strings.Contains(err.Error(), "display override LMDF does not name a display") {
+		t.Errorf("readModuleFiles() error = %v, want the unknown display override", err)
+	}
+}
+
+func TestDisplay_IsSet(t *testing.T) {
+	displays := Displays{}
+	if err := displays.UnmarshalData([]byte(`[{ "name": "Left", "left": 0, "width": -5 }]`)); err != nil {
+		t.Fatal(err)
+	}
+	built := Display{Name: "Built", Width: 600}
+	tests := []struct {
+		display *Display
+		field   string
+		want    bool
+	}{
+		{display: &displays[0], field: "left", want: true},
+		{display: &displays[0], field: "width", want: true},
+		{display: &displays[0], field: "top", want: false},
+		{display: &built, field: "width", want: true},
+		{display: &built, field: "left", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.display.Name+"."+tt.field, func(t *testing.T) {
+			if got := tt.display.IsSet(tt.field); got != tt.want {
+				t.Errorf("IsSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplay_MarshalJSON(t *testing.T) {
+	displays := Displays{}
+	if err := displays.UnmarshalData([]byte(`[{ "name": "Left", "left": 0, "top": -200, "opacity": 0 }]`)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		display Display
+		want    string
+	}{
+		{name: "File", display: displays[0], want: `{"name":"Left","left":0,"top":-200,"opacity":0,"enabled":true}`},
+		{name: "Built", display: Display{Name: "Built", Width: 600}, want: `{"name":"Built","width":600}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.display)
+			if err != nil {
+				t.Fatalf("MarshalJSON() error = %v", err)
+			}
+			if got := string(data); got != tt.want {
+				t.Errorf("MarshalJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfiguration_MarshalJSON(t *testing.T) {
+	var config Configuration
+	if err := json.Unmarshal([]byte(`{ "name": "Zero", "fileName": "zero.png", "left": 0, "opacity": 0, "subConfigDef": [] }`), &config); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+	want := `{"name":"Zero","fileName":"zero.png","opacity":0,"left":0,"subConfigDef":[]}`
+	if got := string(data); got != want {
+		t.Errorf("MarshalJSON() = %v, want %v", got, want)
+	}
+}
+
+func TestConfiguration_Explain(t *testing.T) {
+	registry := loadTestModules(t)
+	tests := []struct {
+		argument string
+		want     string
+	}{
+		{
+			argument: "F-14RHV/LMFD_TomcatRIO/BIT.opacity",
+			want:     "F-14RHV/LMFD_TomcatRIO/BIT.opacity = 0.5\n  inherited from parent F-14RHV/LMFD_TomcatRIO\n  set by display LMFD\n",
+		},
+		{
+			argument: "F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected.Opacity",
+			want:     "F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected.opacity = 0.85\n  set by file F-14BRIOHV.json\n",
+		},
+		{
+			argument: "F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected.enabled",
+			want:     "F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected.enabled = true\n  set by file F-14BRIOHV.json\n",
+		},
+		{
+			argument: "F-14RHV/RMFD_TomcatRIO.useAsSwitchX",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.argument, func(t *testing.T) {
+			got, err := explainField(registry, tt.argument)
+			if (err != nil) != (len(tt.want) == 0) {
+				t.Fatalf("explainField() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("explainField() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResolvedModule(t *testing.T) {
+	registry := loadTestModules(t)
+	module, _ := registry.GetByName("F-14RHV")
+	resolved := newResolvedModule(module)
+	data, err := json.Marshal(resolved)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded resolvedModule
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	bit := decoded.Configurations[0].Configurations[0].Configurations[0]
+	if bit.Path != "F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected" || bit.XOffsetStart != 0 || bit.Width != 100 {
+		t.Errorf("resolved BIT_Selected = %+v", bit)
+	}
+	if decoded.Configurations[0].Display != "LMFD" {
+		t.Errorf("resolved LMFD_TomcatRIO display = %q, want LMFD", decoded.Configurations[0].Display)
+	}
+}
+
+func TestResolveDump(t *testing.T) {
+	registry := loadTestModules(t)
+	tests := []struct {
+		module    string
+		subModule string
+		want      []string
+	}{
+		{module: "F-14RHV", want: []string{"F-14RHV/LMFD_TomcatRIO", "F-14RHV/WHKEY_TomcatRIO", "F-14RHV/RMFD_TomcatRIO"}},
+		{module: "F-14RHV", subModule: "WHKEY_TomcatRIO", want: []string{"F-14RHV/WHKEY_TomcatRIO"}},
+		{module: "F-14RHV", subModule: "LMFD_TomcatRIO/BIT", want: []string{"F-14RHV/LMFD_TomcatRIO/BIT"}},
+	}
+	for _, tt := range tests {
+		t.Run(JoinAddress(tt.module, tt.subModule), func(t *testing.T) {
+			modules, err := resolveDump(registry, JoinAddress(tt.module, tt.subModule))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(modules) != 1 {
+				t.Fatalf("resolveDump() returned %d modules, want 1", len(modules))
+			}
+			var got []string
+			for _, config := range modules[0].Configurations {
+				got = append(got, config.Path)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("resolveDump() configurations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Describes the module -new-module generates
+type ModuleScaffold struct {
+	Name        string
+	Tag         string
+	DisplayName string
+	// The category directory below the Modules root, such as Jets\Navy
+	Category string
+	// The source image, relative to the configured file path like the fileName of a module
+	SourceImage string
+	// The displays that get a starter configuration
+	Displays []string
+}
+
+// The module file written by -new-module, only the values of a starter module are written
+type scaffoldModuleFile struct {
+	Modules []scaffoldModule `json:"modules"`
+}
+
+type scaffoldModule struct {
+	Name           string                  `json:"name"`
+	Tag            string                  `json:"tag"`
+	DisplayName    string                  `json:"displayName"`
+	FileName       string                  `json:"fileName"`
+	Configurations []scaffoldConfiguration `json:"configurations"`
+}
+
+type scaffoldConfiguration struct {
+	Name          string `json:"name"`
+	Width         int    `json:"width"`
+	Height        int    `json:"height"`
+	XOffsetStart  int    `json:"xOffsetStart"`
+	XOffsetFinish int    `json:"xOffsetFinish"`
+	YOffsetStart  int    `json:"yOffsetStart"`
+	YOffsetFinish int    `json:"yOffsetFinish"`
+}
+
+// Returns the path of a source image named like the fileName of a module
+func sourceImagePath(fileName string) string {
+	fileName = os.ExpandEnv(fileName)
+	if filepath.IsAbs(fileName) || configurationInstance == nil {
+		return fileName
+	}
+	return filepath.Join(configurationInstance.FilePath, strings.ReplaceAll(fileName, "\\", string(filepath.Separator)))
+}
+
+// Reads the dimensions of a JPEG or PNG image without decoding its pixels
+func readImageSize(ctx context.Context, filename string) (image.Point, error) {
+	type result struct {
+		size image.Point
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		file, err := os.Open(filename)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer file.Close()
+		config, _, err := image.DecodeConfig(file)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", filename, err)
+		}
+		done <- result{image.Point{X: config.Width, Y: config.Height}, err}
+	}()
+	select {
+	case r := <-done:
+		return r.size, r.err
+	case <-ctx.Done():
+		return image.Point{}, &CancelledError{Operation: "the size of " + filename, Err: ctx.Err()}
+	}
+}
+
+// Returns the offsets of the display clamped to the source image, unset offsets span the whole image
+func clampOffsets(display *Display, size image.Point) (Offsets, error) {
+	clamp := func(field string, value int, fallback int, limit int) int {
+		if !display.IsSet(field) {
+			value = fallback
+		}
+		return max(0, min(value, limit))
+	}
+	offsets := Offsets{
+		XOffsetStart:  clamp("xOffsetStart", display.XOffsetStart, 0, size.X),
+		XOffsetFinish: clamp("xOffsetFinish", display.XOffsetFinish, size.X, size.X),
+		YOffsetStart:  clamp("yOffsetStart", display.YOffsetStart, 0, size.Y),
+		YOffsetFinish: clamp("yOffsetFinish", display.YOffsetFinish, size.Y, size.Y),
+	}
+	if offsets.XOffsetFinish <= offsets.XOffsetStart || offsets.YOffsetFinish <= offsets.YOffsetStart {
+		return offsets, fmt.Errorf("the offsets of display %s lie outside the %dx%d source image", display.Name, size.X, size.Y)
+	}
+	return offsets, nil
+}
+
+// Builds the starter module, with a configuration for each display sized from its offsets
+func (s *ModuleScaffold) Generate(displays Displays, size image.Point) (*scaffoldModule, error) {
+	if len(s.Name) == 0 || len(s.SourceImage) == 0 || len(s.Displays) == 0 {
+		return nil, fmt.Errorf("a new module needs a name, a source image and at least one display")
+	}
+	generated := &scaffoldModule{
+		Name:        s.Name,
+		Tag:         s.Tag,
+		DisplayName: s.DisplayName,
+		FileName:    s.SourceImage,
+	}
+	if len(generated.Tag) == 0 {
+		generated.Tag = s.Name
+	}
+	if len(generated.DisplayName) == 0 {
+		generated.DisplayName = s.Name
+	}
+	for _, displayName := range s.Displays {
+		display := displays.findByName(displayName)
+		if display == nil {
+			return nil, fmt.Errorf("display %s is not defined", displayName)
+		}
+		offsets, err := clampOffsets(display, size)
+		if err != nil {
+			return nil, err
+		}
+		// The display is found from the prefix of the configuration name
+		generated.Configurations = append(generated.Configurations, scaffoldConfiguration{
+			Name:          display.Name + "_" + s.Name,
+			Width:         offsets.XOffsetFinish - offsets.XOffsetStart,
+			Height:        offsets.YOffsetFinish - offsets.YOffsetStart,
+			XOffsetStart:  offsets.XOffsetStart,
+			XOffsetFinish: offsets.XOffsetFinish,
+			YOffsetStart:  offsets.YOffsetStart,
+			YOffsetFinish: offsets.YOffsetFinish,
+		})
+	}
+	return generated, nil
+}
+
+// Returns the display with the name, ignoring case
+func (displays Displays) findByName(name string) *Display {
+	for i := range displays {
+		if strings.EqualFold(displays[i].Name, name) {
+			return &displays[i]
+		}
+	}
+	return nil
+}
+
+// Generates the module file below the Modules root and checks that it loads, returns the path of the file
+func (s *ModuleScaffold) Write(ctx context.Context, root string, registry *ModuleRegistry, displays Displays) (string, error) {
+	if existing, ok := registry.GetByName(s.Name); ok {
+		return "", fmt.Errorf("module %s is already defined in %s", s.Name, existing.SourceFile)
+	}
+	size, err := readImageSize(ctx, sourceImagePath(s.SourceImage))
+	if err != nil {
+		return "", err
+	}
+	generated, err := s.Generate(displays, size)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(scaffoldModuleFile{Modules: []scaffoldModule{*generated}}); err != nil {
+		return "", err
+	}
+	category := strings.ReplaceAll(s.Category, categorySeparator, string(filepath.Separator))
+	filename := filepath.Join(root, category, s.Name+".json")
+	if _, err := os.Stat(filename); !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%s already exists", filename)
+	}
+	if err := writeFileAtomic(filename, buffer.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	if _, err := readModuleFile(ctx, filename, newIncludeResolver(ctx, root), &displays); err != nil {
+		os.Remove(filename)
+		return "", fmt.Errorf("the generated module does not load: %w", err)
+	}
+	return filename, nil
+}
+
+// Splits a comma separated list of names, ignoring blanks
+func splitNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes a blank PNG of the size
+func writeTestImage(t *testing.T, filename string, width int, height int) {
+	t.Helper()
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModuleScaffold_Write(t *testing.T) {
+	displays := loadTestDisplays(t)
+	imageDir := t.TempDir()
+	configurationInstance = &MfdConfig{FilePath: imageDir}
+	writeTestImage(t, filepath.Join(imageDir, "Tomcat.png"), 1600, 600)
+
+	root := writeModuleFiles(t, map[string]string{
+		"Existing.json": `{ "modules": [{ "name": "F-14RHV" }] }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewModuleRegistry(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scaffold := ModuleScaffold{Name: "F-14New", Tag: "F-14B", Category: "Jets\\Navy", SourceImage: "Tomcat.png", Displays: []string{"lmfd", "RMFD"}}
+	filename, err := scaffold.Write(context.Background(), root, registry, displays)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if want := filepath.Join(root, "Jets", "Navy", "F-14New.json"); filename != want {
+		t.Errorf("Write() = %v, want %v", filename, want)
+	}
+	generated, err := readModuleFile(context.Background(), filename, newIncludeResolver(context.Background(), root), &displays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs := generated[0].Configurations
+	if len(configs) != 2 || configs[0].Name != "LMFD_F-14New" || configs[0].Display == nil || configs[0].Display.Name != "LMFD" {
+		t.Fatalf("Write() configurations = %+v", configs)
+	}
+	// The LMFD offsets of 101-776 by 250-900 are clamped to the 1600x600 image
+	wantOffsets := Offsets{XOffsetStart: 101, XOffsetFinish: 776, YOffsetStart: 250, YOffsetFinish: 600}
+	if got, _ := configs[0].GetOffset(); got != wantOffsets {
+		t.Errorf("GetOffset() = %+v, want %+v", got, wantOffsets)
+	}
+	if configs[0].Width != 675 || configs[0].Height != 350 {
+		t.Errorf("size = %dx%d, want 675x350", configs[0].Width, configs[0].Height)
+	}
+
+	if _, err := scaffold.Write(context.Background(), root, registry, displays); err == nil {
+		t.Errorf("Write() error = nil, want the existing file")
+	}
+	tests := []ModuleScaffold{
+		{Name: "F-14RHV", SourceImage: "Tomcat.png", Displays: []string{"LMFD"}},
+		{Name: "Other", SourceImage: "Missing.png", Displays: []string{"LMFD"}},
+		{Name: "Other", SourceImage: "Tomcat.png", Displays: []string{"HUD"}},
+		{Name: "Other", SourceImage: "Tomcat.png"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.Write(context.Background(), root, registry, displays); err == nil {
+			t.Errorf("Write(%+v) error = nil, want error", tt)
+		}
+	}
+}
+
+func TestClampOffsets(t *testing.T) {
+	size := image.Point{X: 100, Y: 50}
+	tests := []struct {
+		name    string
+		display Display
+		want    Offsets
+		wantErr bool
+	}{
+		{name: "inside", display: Display{XOffsetStart: 10, XOffsetFinish: 60, YOffsetStart: 5, YOffsetFinish: 45}, want: Offsets{10, 60, 5, 45}},
+		{name: "unset", display: Display{}, want: Offsets{0, 100, 0, 50}},
+		{name: "outside", display: Display{XOffsetStart: 200, XOffsetFinish: 300, YOffsetStart: 0, YOffsetFinish: 10}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clampOffsets(&tt.display, size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clampOffsets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("clampOffsets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scores of the fuzzy matcher, matches at word starts and runs of consecutive matches rank higher
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusConsecutive = 8
+	fuzzyBonusWordStart   = 8
+	fuzzyPenaltyGap       = 1
+	fuzzyPenaltyMissing   = 24
+	fuzzyNoScore          = -1 << 30
+)
+
+// The number of results -search prints and the number of did-you-mean suggestions
+const (
+	searchResultLimit = 10
+	suggestionLimit   = 3
+)
+
+// Ways the matcher reaches a cell of its score table
+const (
+	fuzzySkipText = iota
+	fuzzySkipQuery
+	fuzzyMatchChar
+	fuzzyMatchRun
+)
+
+// Matches the query against the text ignoring case, the query characters must appear in order but may be
+// spread out, and up to a third of them may be missing to tolerate typos. Returns the score and the
+// positions of the matched runes
+func fuzzyMatch(query string, text string) (int, []int, bool) {
+	q := []rune(strings.ToLower(query))
+	original := []rune(text)
+	t := []rune(strings.ToLower(text))
+	n, m := len(q), len(t)
+	if n == 0 || m == 0 {
+		return 0, nil, false
+	}
+
+	// best[i][j] is the best score for the first i query runes within the first j text runes,
+	// matched[i][j] the best score when query rune i-1 is matched at text rune j-1
+	best := make([][]int, n+1)
+	matched := make([][]int, n+1)
+	bestChoice := make([][]int, n+1)
+	matchedChoice := make([][]int, n+1)
+	for i := range best {
+		best[i] = make([]int, m+1)
+		matched[i] = make([]int, m+1)
+		bestChoice[i] = make([]int, m+1)
+		matchedChoice[i] = make([]int, m+1)
+	}
+	for i := 1; i <= n; i++ {
+		best[i][0] = best[i-1][0] - fuzzyPenaltyMissing
+		bestChoice[i][0] = fuzzySkipQuery
+		matched[i][0] = fuzzyNoScore
+	}
+	for i := 0; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			matched[i][j] = fuzzyNoScore
+			if i == 0 {
+				continue
+			}
+			if q[i-1] == t[j-1] {
+				score, choice := best[i-1][j-1], fuzzyMatchChar
+				if run := matched[i-1][j-1] + fuzzyBonusConsecutive; matched[i-1][j-1] != fuzzyNoScore && run > score {
+					score, choice = run, fuzzyMatchRun
+				}
+				matched[i][j] = score + fuzzyScoreMatch + wordStartBonus(original, j-1)
+				matchedChoice[i][j] = choice
+			}
+
+			best[i][j], bestChoice[i][j] = best[i][j-1]-fuzzyPenaltyGap, fuzzySkipText
+			if matched[i][j] > best[i][j] {
+				best[i][j], bestChoice[i][j] = matched[i][j], fuzzyMatchChar
+			}
+			if missing := best[i-1][j] - fuzzyPenaltyMissing; missing > best[i][j] {
+				best[i][j], bestChoice[i][j] = missing, fuzzySkipQuery
+			}
+		}
+	}
+
+	var positions []int
+	i, j, inMatch := n, m, false
+	for i > 0 {
+		if inMatch {
+			positions = append(positions, j-1)
+			inMatch = matchedChoice[i][j] == fuzzyMatchRun
+			i, j = i-1, j-1
+			continue
+		}
+		switch bestChoice[i][j] {
+		case fuzzySkipText:
+			j--
+		case fuzzySkipQuery:
+			i--
+		default:
+			inMatch = true
+		}
+	}
+	if len(positions) == 0 || n-len(positions) > n/3 {
+		return 0, nil, false
+	}
+	sort.Ints(positions)
+	return best[n][m], positions, true
+}
+
+// Rewards matches at the start of the text, after a separator and at a lower to upper case change
+func wordStartBonus(text []rune, index int) int {
+	if index == 0 {
+		return fuzzyBonusWordStart
+	}
+	previous, current := text[index-1], text[index]
+	if !unicode.IsLetter(previous) && !unicode.IsDigit(previous) {
+		return fuzzyBonusWordStart
+	}
+	if unicode.IsLower(previous) && unicode.IsUpper(current) {
+		return fuzzyBonusWordStart
+	}
+	return 0
+}
+
+// Stores a module found by a search and the field that matched best
+type SearchResult struct {
+	Module    *Module
+	Field     string
+	Text      string
+	Score     int
+	Positions []int
+}
+
+// Returns the matched text with the matched runs in brackets, such as [F-14]B [Tom]cat
+func (result SearchResult) Highlight() string {
+	text := []rune(result.Text)
+	matched := map[int]bool{}
+	for _, position := range result.Positions {
+		matched[position] = true
+	}
+	var sb strings.Builder
+	for i, c := range text {
+		if matched[i] && !matched[i-1] {
+			sb.WriteRune('[')
+		}
+		sb.WriteRune(c)
+		if matched[i] && !matched[i+1] {
+			sb.WriteRune(']')
+		}
+	}
+	return sb.String()
+}
+
+// Fuzzy matches the query across the name, tag, display name and category of every module,
+// returning at most limit results with the best first
+func (r *ModuleRegistry) Search(query string, limit int) []SearchResult {
+	var results []SearchResult
+	r.ForEach(func(m *Module) bool {
+		fields := []struct{ name, text string }{
+			{"name", m.Name},
+			{"tag", m.Tag},
+			{"displayName", m.DisplayName},
+			{"category", m.Category},
+		}
+		var moduleBest *SearchResult
+		for _, field := range fields {
+			score, positions, ok := fuzzyMatch(query, field.text)
+			if ok && (moduleBest == nil || score > moduleBest.Score) {
+				moduleBest = &SearchResult{Module: m, Field: field.name, Text: field.text, Score: score, Positions: positions}
+			}
+		}
+		if moduleBest != nil {
+			results = append(results, *moduleBest)
+		}
+		return true
+	})
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Module.Name < results[j].Module.Name
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Returns the names of the modules that best match a key that did not resolve
+func (r *ModuleRegistry) Suggest(key string) []string {
+	var names []string
+	for _, result := range r.Search(key, suggestionLimit) {
+		names = append(names, result.Module.Name)
+	}
+	return names
+}
+
+// Prints the ranked results of a -search query
+func printSearchResults(registry *ModuleRegistry, query string) {
+	results := registry.Search(query, searchResultLimit)
+	if len(results) == 0 {
+		fmt.Printf("No modules match %q\n", query)
+		return
+	}
+	for _, result := range results {
+		fmt.Printf("%s\t%s: %s\n", result.Module.Name, result.Field, result.Highlight())
+	}
+}
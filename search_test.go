@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query     string
+		text      string
+		want      bool
+		positions []int
+	}{
+		{query: "tomcat", text: "F-14B Tomcat (RIO)", want: true, positions: []int{6, 7, 8, 9, 10, 11}},
+		{query: "F14", text: "F-14B", want: true, positions: []int{0, 2, 3}},
+		{query: "f-14pvh", text: "F-14PHV", want: true, positions: []int{0, 1, 2, 3, 4, 5}},
+		{query: "zzz", text: "F-14PHV", want: false},
+		{query: "", text: "F-14PHV", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, positions, ok := fuzzyMatch(tt.query, tt.text)
+			if ok != tt.want {
+				t.Fatalf("fuzzyMatch() ok = %v, want %v", ok, tt.want)
+			}
+			if !reflect.DeepEqual(positions, tt.positions) {
+				t.Errorf("fuzzyMatch() positions = %v, want %v", positions, tt.positions)
+			}
+		})
+	}
+}
+
+func TestModuleRegistry_Search(t *testing.T) {
+	registry, err := NewModuleRegistry(testModules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query     string
+		first     string
+		highlight string
+		count     int
+	}{
+		{query: "tomcat rio", first: "F-14RHV", highlight: "F-14B [Tomcat ]([RIO]) Hi Viz", count: 2},
+		{query: "warthog", first: "A-10C", highlight: "A-10C [Warthog]", count: 1},
+		{query: "navy", first: "F-14PHV", highlight: "Jets\\[Navy]", count: 2},
+		{query: "A10", first: "A-10C", highlight: "[A]-[10]C", count: 1},
+		{query: "qqq", count: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results := registry.Search(tt.query, searchResultLimit)
+			if len(results) != tt.count {
+				t.Fatalf("Search() = %d results, want %d", len(results), tt.count)
+			}
+			if tt.count == 0 {
+				return
+			}
+			if got := results[0].Module.Name; got != tt.first {
+				t.Errorf("Search() first = %v, want %v", got, tt.first)
+			}
+			if got := results[0].Highlight(); got != tt.highlight {
+				t.Errorf("Highlight() = %v, want %v", got, tt.highlight)
+			}
+		})
+	}
+}
+
+func TestModuleRegistry_Find_Suggestions(t *testing.T) {
+	registry, err := NewModuleRegistry(testModules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = registry.Find("F-14PVH")
+	if err == nil || !strings.Contains(err.Error(), "did you mean F-14PHV, F-14RHV?") {
+		t.Errorf("Find() error = %v, want did you mean F-14PHV, F-14RHV?", err)
+	}
+	_, err = registry.Find("qqq")
+	if err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("Find() error = %v, want no suggestions", err)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The number of addresses kept in the recents list
+const maxRecents = 10
+
+// Stores what the user selected between launches
+type UserState struct {
+	// The address of the last selected module or configuration
+	LastSelection string `json:"lastSelection,omitempty"`
+	// The active child of each switch, keyed by the address of the switch
+	Switches map[string]string `json:"switches,omitempty"`
+	// The most recently selected addresses, the most recent first
+	Recents   []string `json:"recents,omitempty"`
+	Favorites []string `json:"favorites,omitempty"`
+}
+
+func getStateFilePath() string {
+	return filepath.Join(getSavedGamesFolder(), "MFDMF", "state.json")
+}
+
+// Loads the state file, a missing file is an empty state and a corrupt file is moved aside and replaced by an empty state
+func LoadUserState(filename string) (*UserState, error) {
+	state := &UserState{}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		corruptFile := filename + ".corrupt"
+		if renameErr := os.Rename(filename, corruptFile); renameErr != nil {
+			return nil, fmt.Errorf("state file %s is corrupt and could not be moved aside: %w", filename, renameErr)
+		}
+		logger.Log(fmt.Sprintf("State file %s is corrupt (%v), moved it to %s and started from an empty state", filename, err, corruptFile))
+		return &UserState{}, nil
+	}
+	return state, nil
+}
+
+// Writes the state file atomically
+func (s *UserState) Save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data, 0644)
+}
+
+// Writes the data to a temporary file next to the target and renames it over the target, so readers
+// never see a partially written file
+func writeFileAtomic(filename string, data []byte, perm fs.FileMode) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), filename)
+}
+
+// Records the address as the last selection and moves it to the front of the recents
+func (s *UserState) Select(address string) {
+	s.LastSelection = address
+	recents := []string{address}
+	for _, recent := range s.Recents {
+		if recent != address && len(recents) < maxRecents {
+			recents = append(recents, recent)
+		}
+	}
+	s.Recents = recents
+}
+
+// Adds the address to the favorites, returns false when it already is one
+func (s *UserState) AddFavorite(address string) bool {
+	for _, favorite := range s.Favorites {
+		if favorite == address {
+			return false
+		}
+	}
+	s.Favorites = append(s.Favorites, address)
+	return true
+}
+
+// Removes the address from the favorites, returns false when it is not one
+func (s *UserState) RemoveFavorite(address string) bool {
+	for i, favorite := range s.Favorites {
+		if favorite == address {
+			s.Favorites = append(s.Favorites[:i], s.Favorites[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Remembers the active child of the switch
+func (s *UserState) SetSwitch(config *Configuration) {
+	if s.Switches == nil {
+		s.Switches = map[string]string{}
+	}
+	s.Switches[config.GetPath()] = config.GetActiveChild().Name
+}
+
+// Selects the remembered child of every switch. Entries that do not resolve are skipped but kept, the
+// module file may only have failed to load this time, -prune-state removes them
+func (s *UserState) ApplySwitches(registry *ModuleRegistry) {
+	for address, active := range s.Switches {
+		if err := applySwitch(registry, address, active); err != nil {
+			logger.Log(fmt.Sprintf("Ignoring the saved state of switch %s: %v", address, err))
+		}
+	}
+}
+
+func applySwitch(registry *ModuleRegistry, address string, active string) error {
+	match, err := registry.Resolve(address)
+	if err != nil {
+		return err
+	}
+	if match.Configuration == nil {
+		return fmt.Errorf("%s is a module, not a switch", address)
+	}
+	return match.Configuration.SelectChild(active)
+}
+
+// Removes the switch states, favorites and recents that no longer resolve, returns what was removed
+func (s *UserState) Prune(registry *ModuleRegistry) []string {
+	var removed []string
+	for address, active := range s.Switches {
+		if err := applySwitch(registry, address, active); err != nil {
+			removed = append(removed, fmt.Sprintf("switch %s: %v", address, err))
+			delete(s.Switches, address)
+		}
+	}
+	resolved := func(kind string, addresses []string) []string {
+		kept := []string{}
+		for _, address := range addresses {
+			if _, err := registry.Resolve(address); err != nil {
+				removed = append(removed, fmt.Sprintf("%s %s: %v", kind, address, err))
+				continue
+			}
+			kept = append(kept, address)
+		}
+		return kept
+	}
+	s.Favorites = resolved("favorite", s.Favorites)
+	s.Recents = resolved("recent", s.Recents)
+	sort.Strings(removed)
+	return removed
+}
+
+// Applies a -set-state argument such as switch=F-14RHV/LMFD_TomcatRIO/NAV
+func (s *UserState) Set(registry *ModuleRegistry, argument string) error {
+	key, address, found := strings.Cut(argument, "=")
+	if !found || len(address) == 0 {
+		return fmt.Errorf("%s must be key=address, the keys are selection, switch, favorite and unfavorite", argument)
+	}
+	match, err := registry.Resolve(address)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "selection":
+		s.Select(match.Path)
+	case "switch":
+		parent := match.Configuration
+		if parent != nil {
+			parent = parent.Parent
+		}
+		if parent == nil || !parent.IsSwitch() {
+			return fmt.Errorf("%s is not a state of a switch", match.Path)
+		}
+		if err := parent.SelectChild(match.Configuration.Name); err != nil {
+			return err
+		}
+		s.SetSwitch(parent)
+	case "favorite":
+		if !s.AddFavorite(match.Path) {
+			return fmt.Errorf("%s is already a favorite", match.Path)
+		}
+	case "unfavorite":
+		if !s.RemoveFavorite(match.Path) {
+			return fmt.Errorf("%s is not a favorite", match.Path)
+		}
+	default:
+		return fmt.Errorf("unknown state key %s, the keys are selection, switch, favorite and unfavorite", key)
+	}
+	return nil
+}
+
+// Returns a readable listing of the state
+func (s *UserState) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Last selection: %s\n", s.LastSelection)
+	sb.WriteString("Switches:\n")
+	addresses := make([]string, 0, len(s.Switches))
+	for address := range s.Switches {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		fmt.Fprintf(&sb, "  %s = %s\n", address, s.Switches[address])
+	}
+	sb.WriteString("Recents:\n")
+	for _, recent := range s.Recents {
+		fmt.Fprintf(&sb, "  %s\n", recent)
+	}
+	sb.WriteString("Favorites:\n")
+	for _, favorite := range s.Favorites {
+		fmt.Fprintf(&sb, "  %s\n", favorite)
+	}
+	return sb.String()
+}
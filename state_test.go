@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadUserState(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "state.json")
+
+	state, err := LoadUserState(filename)
+	if err != nil || !reflect.DeepEqual(state, &UserState{}) {
+		t.Fatalf("LoadUserState() = %v, %v, want an empty state for a missing file", state, err)
+	}
+
+	state.Select("F-14RHV")
+	state.AddFavorite("F-14RHV/LMFD_TomcatRIO")
+	state.Switches = map[string]string{"F-14RHV/LMFD_TomcatRIO": "NAV"}
+	if err := state.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadUserState(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, state) {
+		t.Errorf("LoadUserState() = %+v, want %+v", loaded, state)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Save() left %d files, want 1", len(entries))
+	}
+}
+
+func TestLoadUserState_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(filename, []byte(`{"lastSelection": "F-14`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state, err := LoadUserState(filename)
+	if err != nil || !reflect.DeepEqual(state, &UserState{}) {
+		t.Fatalf("LoadUserState() = %v, %v, want an empty state", state, err)
+	}
+	if _, err := os.Stat(filename + ".corrupt"); err != nil {
+		t.Errorf("the corrupt file was not moved aside: %v", err)
+	}
+}
+
+func TestUserState_Select(t *testing.T) {
+	state := &UserState{}
+	for i := 0; i < maxRecents+2; i++ {
+		state.Select(string(rune('A' + i)))
+	}
+	state.Select("C")
+	if len(state.Recents) != maxRecents {
+		t.Errorf("len(Recents) = %v, want %v", len(state.Recents), maxRecents)
+	}
+	if state.Recents[0] != "C" || state.Recents[1] != "L" || state.LastSelection != "C" {
+		t.Errorf("Recents = %v, want C first then L", state.Recents)
+	}
+}
+
+func TestUserState_Set(t *testing.T) {
+	registry := loadTestModules(t)
+	state := &UserState{}
+	tests := []struct {
+		argument string
+		wantErr  bool
+	}{
+		{argument: "switch=F-14RHV/LMFD_TomcatRIO/NAV"},
+		{argument: "favorite=F-14RHV/LMFD_TomcatRIO/BIT"},
+		{argument: "favorite=F-14RHV/LMFD_TomcatRIO/BIT", wantErr: true},
+		{argument: "unfavorite=F-14RHV/LMFD_TomcatRIO/BIT"},
+		{argument: "selection=F-14RHV/RMFD_TomcatRIO"},
+		{argument: "switch=F-14RHV/WHKEY_TomcatRIO", wantErr: true},
+		{argument: "color=F-14RHV", wantErr: true},
+		{argument: "favorite", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := state.Set(registry, tt.argument); (err != nil) != tt.wantErr {
+			t.Errorf("Set(%s) error = %v, wantErr %v", tt.argument, err, tt.wantErr)
+		}
+	}
+	want := &UserState{
+		LastSelection: "F-14RHV/RMFD_TomcatRIO",
+		Switches:      map[string]string{"F-14RHV/LMFD_TomcatRIO": "NAV"},
+		Recents:       []string{"F-14RHV/RMFD_TomcatRIO"},
+		Favorites:     []string{},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("Set() state = %+v, want %+v", state, want)
+	}
+
+	// A fresh load of the modules starts on BIT until the saved switches are applied
+	registry = loadTestModules(t)
+	state.Switches["F-14RHV/Missing"] = "BIT"
+	state.ApplySwitches(registry)
+	match, _ := registry.Resolve("F-14RHV/LMFD_TomcatRIO")
+	if got := match.Configuration.GetActiveChild().Name; got != "NAV" {
+		t.Errorf("ApplySwitches() active = %v, want NAV", got)
+	}
+	// A module that failed to load this time keeps its saved state
+	if _, ok := state.Switches["F-14RHV/Missing"]; !ok {
+		t.Errorf("ApplySwitches() dropped the state of a switch that did not resolve")
+	}
+
+	state.Favorites = []string{"F-14RHV", "F-15E"}
+	removed := state.Prune(registry)
+	if len(removed) != 2 || !strings.HasPrefix(removed[0], "favorite F-15E") || !strings.HasPrefix(removed[1], "switch F-14RHV/Missing") {
+		t.Errorf("Prune() removed %v, want the F-15E favorite and the missing switch", removed)
+	}
+	want = &UserState{
+		LastSelection: "F-14RHV/RMFD_TomcatRIO",
+		Switches:      map[string]string{"F-14RHV/LMFD_TomcatRIO": "NAV"},
+		Recents:       []string{"F-14RHV/RMFD_TomcatRIO"},
+		Favorites:     []string{"F-14RHV"},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("Prune() state = %+v, want %+v", state, want)
+	}
+}
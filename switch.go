@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Determines if the children of the configuration are mutually exclusive states
+func (config *Configuration) IsSwitch() bool {
+	return config.UseAsSwitch && len(config.Configurations) > 0
+}
+
+// Returns the active child of a switch, nil when the configuration is not a switch
+func (config *Configuration) GetActiveChild() *Configuration {
+	if !config.IsSwitch() {
+		return nil
+	}
+	return &config.Configurations[config.activeChild]
+}
+
+// Makes the named child the active state of the switch
+func (config *Configuration) SelectChild(name string) error {
+	if !config.IsSwitch() {
+		return fmt.Errorf("configuration %s is not a switch", config.GetPath())
+	}
+	for i := range config.Configurations {
+		if config.Configurations[i].Name == name {
+			config.setActiveChild(i)
+			return nil
+		}
+	}
+	names := make([]string, len(config.Configurations))
+	for i := range config.Configurations {
+		names[i] = config.Configurations[i].Name
+	}
+	return fmt.Errorf("switch %s has no state %s, expected one of %s", config.GetPath(), name, strings.Join(names, ", "))
+}
+
+// Activates the next child of the switch, wrapping around after the last one
+func (config *Configuration) SelectNext() *Configuration {
+	return config.cycle(1)
+}
+
+// Activates the previous child of the switch, wrapping around before the first one
+func (config *Configuration) SelectPrevious() *Configuration {
+	return config.cycle(-1)
+}
+
+func (config *Configuration) cycle(step int) *Configuration {
+	if !config.IsSwitch() {
+		return nil
+	}
+	count := len(config.Configurations)
+	config.setActiveChild((config.activeChild + step + count) % count)
+	return config.GetActiveChild()
+}
+
+func (config *Configuration) setActiveChild(index int) {
+	config.activeChild = index
+	config.applySwitchStates()
+}
+
+// Chooses the initial state of the switch from the activeChild set in the module file
+func (config *Configuration) initializeSwitch() error {
+	config.activeChild = 0
+	if len(config.ActiveChild) == 0 || !config.IsSwitch() {
+		return nil
+	}
+	for i := range config.Configurations {
+		if config.Configurations[i].Name == config.ActiveChild {
+			config.activeChild = i
+			return nil
+		}
+	}
+	return fmt.Errorf("switch %s has no state %s", config.GetPath(), config.ActiveChild)
+}
+
+// Enables only the children on the active branch of every switch, the other branches are disabled
+func (config *Configuration) applySwitchStates() {
+	for i := range config.Configurations {
+		child := &config.Configurations[i]
+		child.Enabled = child.cascadeEnabled
+		child.disabledBySwitch = ""
+		switch {
+		case len(config.disabledBySwitch) > 0:
+			child.disabledBySwitch = config.disabledBySwitch
+		case config.IsSwitch() && i != config.activeChild:
+			child.disabledBySwitch = config.GetPath()
+		}
+		if len(child.disabledBySwitch) > 0 {
+			child.Enabled = false
+		}
+		child.applySwitchStates()
+	}
+}
+
+// Sets up the switches below the configurations once the defaults are resolved
+func initializeSwitches(configs []Configuration) error {
+	for i := range configs {
+		currentConfig := &configs[i]
+		if err := currentConfig.initializeSwitch(); err != nil {
+			return err
+		}
+		if err := initializeSwitches(currentConfig.Configurations); err != nil {
+			return err
+		}
+	}
+	for i := range configs {
+		configs[i].applySwitchStates()
+	}
+	return nil
+}
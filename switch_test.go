@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// Returns the enabled state of each child of the configuration, keyed by name
+func childStates(config *Configuration) map[string]bool {
+	states := map[string]bool{}
+	for i := range config.Configurations {
+		child := &config.Configurations[i]
+		states[child.Name] = child.Enabled
+		for j := range child.Configurations {
+			states[child.Configurations[j].Name] = child.Configurations[j].Enabled
+		}
+	}
+	return states
+}
+
+func TestConfiguration_Switch(t *testing.T) {
+	registry := loadTestModules(t)
+	match, err := registry.Resolve("F-14RHV/LMFD_TomcatRIO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lmfd := match.Configuration
+	if !lmfd.IsSwitch() {
+		t.Fatalf("IsSwitch() = false, want true")
+	}
+
+	tests := []struct {
+		name   string
+		action func() error
+		active string
+	}{
+		{name: "initial", action: func() error { return nil }, active: "BIT"},
+		{name: "select", action: func() error { return lmfd.SelectChild("NAV") }, active: "NAV"},
+		{name: "next", action: func() error { lmfd.SelectNext(); return nil }, active: "TAC"},
+		{name: "previous", action: func() error { lmfd.SelectPrevious(); return nil }, active: "NAV"},
+		{name: "wrap previous", action: func() error { _ = lmfd.SelectChild("BIT"); lmfd.SelectPrevious(); return nil }, active: "TGT"},
+		{name: "wrap next", action: func() error { lmfd.SelectNext(); return nil }, active: "BIT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.action(); err != nil {
+				t.Fatal(err)
+			}
+			if got := lmfd.GetActiveChild().Name; got != tt.active {
+				t.Errorf("GetActiveChild() = %v, want %v", got, tt.active)
+			}
+			for name, enabled := range childStates(lmfd) {
+				want := name == tt.active || name == tt.active+"_Selected"
+				if enabled != want {
+					t.Errorf("%s enabled = %v, want %v", name, enabled, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfiguration_Switch_NotASwitch(t *testing.T) {
+	registry := loadTestModules(t)
+	match, err := registry.Resolve("F-14RHV/LMFD_TomcatRIO/BIT/BIT_Selected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A switch without children has no states
+	config := match.Configuration
+	if config.IsSwitch() || config.GetActiveChild() != nil || config.SelectNext() != nil {
+		t.Errorf("IsSwitch() = true, want false")
+	}
+	if err := config.SelectChild("BIT"); err == nil {
+		t.Errorf("SelectChild() error = nil, want error")
+	}
+
+	lmfd := match.Configuration.Parent.Parent
+	if err := lmfd.SelectChild("HSD"); err == nil || !strings.Contains(err.Error(), "BIT, SPL, NAV, TAC, DL, TGT") {
+		t.Errorf("SelectChild() error = %v, want the list of states", err)
+	}
+}
+
+func TestConfiguration_Switch_ActiveChild(t *testing.T) {
+	configurationInstance = &MfdConfig{}
+	displays := loadTestDisplays(t)
+	root := writeModuleFiles(t, map[string]string{
+		"Test.json": `{ "modules": [{ "name": "Test", "configurations": [
+			{ "name": "Pages", "displayName": "LMFD", "useAsSwitch": true, "activeChild": "Two",
+			  "subConfigDef": [{ "name": "One" }, { "name": "Two" }] }] }] }`,
+		"Bad.json": `{ "modules": [{ "name": "Bad", "configurations": [
+			{ "name": "Pages", "useAsSwitch": true, "activeChild": "Three", "subConfigDef": [{ "name": "One" }] }] }] }`,
+	})
+	modules, err := readModuleFiles(context.Background(), root, &displays)
+	if err == nil || !strings.Contains(err.Error(), "switch Bad/Pages has no state Three") {
+		t.Errorf("readModuleFiles() error = %v, want the unknown state", err)
+	}
+	if len(modules) != 1 {
+		t.Fatalf("readModuleFiles() = %d modules, want 1", len(modules))
+	}
+	pages := &modules[0].Configurations[0]
+	if got := pages.GetActiveChild().Name; got != "Two" {
+		t.Errorf("GetActiveChild() = %v, want Two", got)
+	}
+	explanation, err := pages.Configurations[0].Explain("enabled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(explanation, "disabled by switch Test/Pages") {
+		t.Errorf("Explain() = %v, want disabled by switch Test/Pages", explanation)
+	}
+}
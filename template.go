@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The object of a module file that declares its configuration templates
+const templatesKey = "templates"
+
+// Instantiates a template in place of the object, the with object supplies the parameters
+const templateKey = "template"
+
+// Generates sibling configurations from a template
+const repeatKey = "repeat"
+
+// The parameter holding the zero based instance number inside a repeat
+const repeatIndexParameter = "index"
+
+// Matches the ${expression} placeholders of a template body
+var templatePlaceholder = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// Stores a template declared in a module file, for example
+//
+//	"templates": { "CapPage": { "parameters": { "color": "blue" }, "body": { "name": "${name}", "xOffsetFinish": "${xOffsetStart + 210}" } } }
+//
+// Parameters hold the default values, a placeholder that is the whole string keeps the type of its value
+type configurationTemplate struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Body       map[string]interface{} `json:"body"`
+}
+
+// Stores a repeat construct, each instance sees its number as ${index} and the matching entry of each as parameters
+//
+//	{ "repeat": { "template": "CapPage", "each": [{ "name": "BIT" }, { "name": "SPL" }], "with": { "xOffsetStart": "${50 + index * 275}" } } }
+type templateRepeat struct {
+	Template string                   `json:"template"`
+	Count    int                      `json:"count"`
+	With     map[string]interface{}   `json:"with"`
+	Each     []map[string]interface{} `json:"each"`
+}
+
+type templateExpander struct {
+	templates map[string]configurationTemplate
+}
+
+// Returns the module file data with every template instance and repeat expanded
+func expandTemplates(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(`"`+templateKey+`"`)) && !bytes.Contains(data, []byte(`"`+repeatKey+`"`)) {
+		return data, nil
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	expander := &templateExpander{templates: map[string]configurationTemplate{}}
+	if templates, ok := document[templatesKey]; ok {
+		if err := remarshal(templates, &expander.templates); err != nil {
+			return nil, fmt.Errorf("templates: %w", err)
+		}
+		delete(document, templatesKey)
+	}
+	expanded, err := expander.expand(document, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(expanded)
+}
+
+// Converts a decoded JSON value into a typed value
+func remarshal(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// Walks the document replacing template instances, stack holds the templates being instantiated
+func (e *templateExpander) expand(value interface{}, stack []string) (interface{}, error) {
+	switch typed := value.(type) {
+	case []interface{}:
+		var expanded []interface{}
+		for _, item := range typed {
+			object, ok := item.(map[string]interface{})
+			if ok && object[repeatKey] != nil {
+				instances, err := e.repeat(object[repeatKey], stack)
+				if err != nil {
+					return nil, err
+				}
+				expanded = append(expanded, instances...)
+				continue
+			}
+			itemValue, err := e.expand(item, stack)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, itemValue)
+		}
+		return expanded, nil
+	case map[string]interface{}:
+		if name, ok := typed[templateKey]; ok {
+			return e.instantiateObject(name, typed, stack)
+		}
+		expanded := map[string]interface{}{}
+		for key, item := range typed {
+			itemValue, err := e.expand(item, stack)
+			if err != nil {
+				return nil, err
+			}
+			expanded[key] = itemValue
+		}
+		return expanded, nil
+	}
+	return value, nil
+}
+
+// Replaces an object holding a template directive, its other keys override the instantiated body
+func (e *templateExpander) instantiateObject(name interface{}, object map[string]interface{}, stack []string) (interface{}, error) {
+	templateName, ok := name.(string)
+	if !ok {
+		return nil, fmt.Errorf("template must be a string")
+	}
+	parameters, _ := object["with"].(map[string]interface{})
+	instance, err := e.instantiate(templateName, parameters, stack)
+	if err != nil {
+		return nil, err
+	}
+	overrides := map[string]interface{}{}
+	for key, item := range object {
+		if key == templateKey || key == "with" {
+			continue
+		}
+		itemValue, err := e.expand(item, stack)
+		if err != nil {
+			return nil, err
+		}
+		overrides[key] = itemValue
+	}
+	return mergeObjects(instance, overrides), nil
+}
+
+// Returns the template body with the parameters substituted
+func (e *templateExpander) instantiate(name string, parameters map[string]interface{}, stack []string) (map[string]interface{}, error) {
+	for _, active := range stack {
+		if active == name {
+			return nil, fmt.Errorf("template cycle %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+	declared, ok := e.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("template %s is not declared", name)
+	}
+	values := map[string]interface{}{}
+	for key, value := range declared.Parameters {
+		values[key] = value
+	}
+	for key, value := range parameters {
+		values[key] = value
+	}
+	body, err := substituteParameters(declared.Body, values)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	expanded, err := e.expand(body, append(append([]string{}, stack...), name))
+	if err != nil {
+		return nil, err
+	}
+	return expanded.(map[string]interface{}), nil
+}
+
+// Generates the instances of a repeat construct
+func (e *templateExpander) repeat(value interface{}, stack []string) ([]interface{}, error) {
+	var construct templateRepeat
+	if err := remarshal(value, &construct); err != nil {
+		return nil, fmt.Errorf("repeat: %w", err)
+	}
+	count := construct.Count
+	if len(construct.Each) > 0 {
+		if count != 0 && count != len(construct.Each) {
+			return nil, fmt.Errorf("repeat of %s has count %d but %d each entries", construct.Template, count, len(construct.Each))
+		}
+		count = len(construct.Each)
+	}
+	if count <= 0 {
+		return nil, fmt.Errorf("repeat of %s needs a positive count or each entries", construct.Template)
+	}
+
+	instances := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		values := map[string]interface{}{repeatIndexParameter: float64(i)}
+		if len(construct.Each) > 0 {
+			for key, item := range construct.Each[i] {
+				values[key] = item
+			}
+		}
+		with, err := substituteParameters(construct.With, values)
+		if err != nil {
+			return nil, fmt.Errorf("repeat of %s, instance %d: %w", construct.Template, i, err)
+		}
+		for key, item := range with.(map[string]interface{}) {
+			if _, ok := values[key]; !ok {
+				values[key] = item
+			}
+		}
+		instance, err := e.instantiate(construct.Template, values, stack)
+		if err != nil {
+			return nil, fmt.Errorf("repeat of %s, instance %d: %w", construct.Template, i, err)
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// Returns a copy of the value with every ${expression} placeholder replaced
+func substituteParameters(value interface{}, parameters map[string]interface{}) (interface{}, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		substituted := map[string]interface{}{}
+		for key, item := range typed {
+			itemValue, err := substituteParameters(item, parameters)
+			if err != nil {
+				return nil, err
+			}
+			substituted[key] = itemValue
+		}
+		return substituted, nil
+	case []interface{}:
+		substituted := make([]interface{}, len(typed))
+		for i, item := range typed {
+			itemValue, err := substituteParameters(item, parameters)
+			if err != nil {
+				return nil, err
+			}
+			substituted[i] = itemValue
+		}
+		return substituted, nil
+	case string:
+		return substituteString(typed, parameters)
+	}
+	return value, nil
+}
+
+// Replaces the placeholders of a string, a placeholder that is the whole string keeps the type of its value
+func substituteString(text string, parameters map[string]interface{}) (interface{}, error) {
+	matches := templatePlaceholder.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text, nil
+	}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(text) {
+		return evaluatePlaceholder(text[matches[0][2]:matches[0][3]], parameters)
+	}
+	var sb strings.Builder
+	last := 0
+	for _, match := range matches {
+		sb.WriteString(text[last:match[0]])
+		value, err := evaluatePlaceholder(text[match[2]:match[3]], parameters)
+		if err != nil {
+			return nil, err
+		}
+		if number, ok := value.(float64); ok {
+			sb.WriteString(strconv.FormatFloat(number, 'f', -1, 64))
+		} else {
+			sb.WriteString(fmt.Sprint(value))
+		}
+		last = match[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), nil
+}
+
+// Returns the parameter named by the placeholder, or the value of its arithmetic expression
+func evaluatePlaceholder(expression string, parameters map[string]interface{}) (interface{}, error) {
+	name := strings.TrimSpace(expression)
+	if value, ok := parameters[name]; ok {
+		return value, nil
+	}
+	return evaluateExpression(expression, func(name string) (float64, error) {
+		value, ok := parameters[name]
+		if !ok {
+			return 0, fmt.Errorf("parameter %s is not set", name)
+		}
+		number, ok := value.(float64)
+		if !ok {
+			return 0, fmt.Errorf("parameter %s is not a number", name)
+		}
+		return number, nil
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const templateModuleFile = `{
+	"templates": {
+		"CapPage": {
+			"parameters": { "color": "blue" },
+			"body": {
+				"name": "${name}",
+				"width": 210,
+				"xOffsetStart": "${xOffsetStart}",
+				"xOffsetFinish": "${xOffsetStart + 210}",
+				"subConfigDef": [
+					{ "name": "${name}_Selected", "fileName": "~MFDisplay_Overlays\\${color}.png", "left": "${left}" }
+				]
+			}
+		}
+	},
+	"modules": [{
+		"name": "F-14RHV",
+		"configurations": [{
+			"name": "LMFD_TomcatRIO",
+			"subConfigDef": [
+				{ "template": "CapPage", "with": { "name": "BIT", "xOffsetStart": 50, "left": 135, "color": "red" }, "height": 469 },
+				{ "repeat": {
+					"template": "CapPage",
+					"each": [{ "name": "SPL" }, { "name": "NAV" }, { "name": "TAC", "color": "green" }],
+					"with": { "xOffsetStart": "${325 + index * 275}", "left": "${135 + index * 125}" }
+				} }
+			]
+		}]
+	}]
+}`
+
+func TestExpandTemplates(t *testing.T) {
+	data, err := expandTemplates([]byte(templateModuleFile))
+	if err != nil {
+		t.Fatalf("expandTemplates() error = %v", err)
+	}
+	var jsonData JSONModuleData
+	if err := json.Unmarshal(data, &jsonData); err != nil {
+		t.Fatalf("expanded module does not decode: %v", err)
+	}
+	pages := jsonData.Modules[0].Configurations[0].Configurations
+	tests := []struct {
+		name          string
+		xOffsetStart  int
+		xOffsetFinish int
+		height        int
+		selectedLeft  int
+		selectedFile  string
+	}{
+		{name: "BIT", xOffsetStart: 50, xOffsetFinish: 260, height: 469, selectedLeft: 135, selectedFile: "~MFDisplay_Overlays\\red.png"},
+		{name: "SPL", xOffsetStart: 325, xOffsetFinish: 535, selectedLeft: 135, selectedFile: "~MFDisplay_Overlays\\blue.png"},
+		{name: "NAV", xOffsetStart: 600, xOffsetFinish: 810, selectedLeft: 260, selectedFile: "~MFDisplay_Overlays\\blue.png"},
+		{name: "TAC", xOffsetStart: 875, xOffsetFinish: 1085, selectedLeft: 385, selectedFile: "~MFDisplay_Overlays\\green.png"},
+	}
+	if len(pages) != len(tests) {
+		t.Fatalf("expandTemplates() generated %d configurations, want %d", len(pages), len(tests))
+	}
+	for i, tt := range tests {
+		page := pages[i]
+		if page.Name != tt.name || page.XOffsetStart != tt.xOffsetStart || page.XOffsetFinish != tt.xOffsetFinish || page.Height != tt.height {
+			t.Errorf("page %d = %s %d-%d height %d, want %s %d-%d height %d", i, page.Name, page.XOffsetStart, page.XOffsetFinish, page.Height, tt.name, tt.xOffsetStart, tt.xOffsetFinish, tt.height)
+		}
+		selected := page.Configurations[0]
+		if selected.Name != tt.name+"_Selected" || selected.Left != tt.selectedLeft || selected.FileName != tt.selectedFile {
+			t.Errorf("page %d overlay = %s left %d %q, want %s_Selected left %d %q", i, selected.Name, selected.Left, selected.FileName, tt.name, tt.selectedLeft, tt.selectedFile)
+		}
+	}
+}
+
+func TestExpandTemplates_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		module string
+		want   string
+	}{
+		{
+			name:   "Undeclared Template",
+			module: `{ "modules": [{ "configurations": [{ "template": "Missing" }] }] }`,
+			want:   "template Missing is not declared",
+		},
+		{
+			name:   "Missing Parameter",
+			module: `{ "templates": { "T": { "body": { "name": "${name}" } } }, "modules": [{ "configurations": [{ "template": "T" }] }] }`,
+			want:   "parameter name is not set",
+		},
+		{
+			name:   "Cycle",
+			module: `{ "templates": { "A": { "body": { "template": "B" } }, "B": { "body": { "template": "A" } } }, "modules": [{ "configurations": [{ "template": "A" }] }] }`,
+			want:   "template cycle A -> B -> A",
+		},
+		{
+			name:   "Count Mismatch",
+			module: `{ "templates": { "T": { "body": {} } }, "modules": [{ "configurations": [{ "repeat": { "template": "T", "count": 2, "each": [{}] } }] }] }`,
+			want:   "count 2 but 1 each entries",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := expandTemplates([]byte(tt.module))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expandTemplates() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubstituteString(t *testing.T) {
+	parameters := map[string]interface{}{"width": float64(300), "name": "LMFD", "index": float64(2)}
+	tests := []struct {
+		text string
+		want interface{}
+	}{
+		{text: "${width}", want: float64(300)},
+		{text: "${width/2}", want: float64(150)},
+		{text: "${width / 2}", want: float64(150)},
+		{text: "${width-index*10}", want: float64(280)},
+		{text: "${name}_Selected", want: "LMFD_Selected"},
+		{text: "page ${index+1} of 3", want: "page 3 of 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := substituteString(tt.text, parameters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("substituteString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
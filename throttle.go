@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Identifies the HOTAS throttle whose key images a configuration should show
+type ThrottleType int
+
+const (
+	// The throttle has not been configured
+	NoThrottle ThrottleType = iota
+	Warthog
+	Cougar
+	Virpil
+	WinWing
+	X56
+)
+
+// Stores the name and image suffix of each throttle type
+var throttleTypes = []struct {
+	Type   ThrottleType
+	Name   string
+	Suffix string
+}{
+	{Warthog, "Warthog", "WH"},
+	{Cougar, "Cougar", "HC"},
+	{Virpil, "Virpil", "VPC"},
+	{WinWing, "WinWing", "WW"},
+	{X56, "X56", "X56"},
+}
+
+func (t ThrottleType) String() string {
+	for _, throttle := range throttleTypes {
+		if throttle.Type == t {
+			return throttle.Name
+		}
+	}
+	return ""
+}
+
+// Returns the suffix that identifies image variants for the throttle
+func (t ThrottleType) Suffix() string {
+	for _, throttle := range throttleTypes {
+		if throttle.Type == t {
+			return throttle.Suffix
+		}
+	}
+	return ""
+}
+
+// Parses a throttle type from its name or image suffix, ignoring case
+func ParseThrottleType(value string) (ThrottleType, error) {
+	if len(value) == 0 {
+		return NoThrottle, nil
+	}
+	for _, throttle := range throttleTypes {
+		if strings.EqualFold(throttle.Name, value) || strings.EqualFold(throttle.Suffix, value) {
+			return throttle.Type, nil
+		}
+	}
+	names := make([]string, len(throttleTypes))
+	for i, throttle := range throttleTypes {
+		names[i] = throttle.Name
+	}
+	return NoThrottle, fmt.Errorf("unknown throttle type %q, expected one of %s", value, strings.Join(names, ", "))
+}
+
+func (t ThrottleType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *ThrottleType) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := ParseThrottleType(value)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// Returns the file name of the image variant for the throttle, THROTTLE.jpg becomes THROTTLE_HC.jpg for the Cougar
+func (t ThrottleType) VariantFileName(fileName string) string {
+	base := fileName
+	ext := ""
+	if dot := strings.LastIndex(fileName, "."); dot > strings.LastIndexAny(fileName, "\\/") {
+		base, ext = fileName[:dot], fileName[dot:]
+	}
+	return base + "_" + t.Suffix() + ext
+}
+
+// Returns the active throttle type, falling back to the legacy useCougar setting
+func (config *MfdConfig) GetThrottleType() ThrottleType {
+	if config.ThrottleType != NoThrottle {
+		return config.ThrottleType
+	}
+	if config.UseCougar {
+		return Cougar
+	}
+	return Warthog
+}
+
+// Points the configuration at the image variant for the throttle when it needs a throttle type. The
+// unsuffixed base image shows the Warthog, so only the other throttles have variants
+func (config *Configuration) ResolveThrottleVariant(throttle ThrottleType) error {
+	if !config.NeedsThrottleType || len(config.FileName) == 0 || throttle == Warthog || throttle == NoThrottle {
+		return nil
+	}
+	variant := throttle.VariantFileName(config.FileName)
+	if _, err := os.Stat(variant); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("configuration %s needs the %s throttle image %s which does not exist", config.Name, throttle, variant)
+		}
+		return err
+	}
+	config.FileName = variant
+	return nil
+}
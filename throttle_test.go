@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseThrottleType(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    ThrottleType
+		wantErr bool
+	}{
+		{value: "", want: NoThrottle},
+		{value: "Warthog", want: Warthog},
+		{value: "cougar", want: Cougar},
+		{value: "HC", want: Cougar},
+		{value: "winwing", want: WinWing},
+		{value: "Saitek", want: NoThrottle, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseThrottleType(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseThrottleType() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseThrottleType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMfdConfig_GetThrottleType(t *testing.T) {
+	if got := (&MfdConfig{}).GetThrottleType(); got != Warthog {
+		t.Errorf("GetThrottleType() = %v, want %v", got, Warthog)
+	}
+	if got := (&MfdConfig{UseCougar: true}).GetThrottleType(); got != Cougar {
+		t.Errorf("GetThrottleType() with useCougar = %v, want %v", got, Cougar)
+	}
+	if got := (&MfdConfig{UseCougar: true, ThrottleType: Virpil}).GetThrottleType(); got != Virpil {
+		t.Errorf("GetThrottleType() with throttleType = %v, want %v", got, Virpil)
+	}
+}
+
+func TestConfiguration_ResolveThrottleVariant(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "DCS F14RIO THROTTLE.JPG")
+	if err := os.WriteFile(filepath.Join(dir, "DCS F14RIO THROTTLE_HC.JPG"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Configuration{Name: "WHKEY_TomcatRIO", FileName: fileName, NeedsThrottleType: true}
+	if err := config.ResolveThrottleVariant(Cougar); err != nil {
+		t.Fatalf("ResolveThrottleVariant() error = %v", err)
+	}
+	if want := filepath.Join(dir, "DCS F14RIO THROTTLE_HC.JPG"); config.FileName != want {
+		t.Errorf("ResolveThrottleVariant() FileName = %q, want %q", config.FileName, want)
+	}
+
+	config = &Configuration{Name: "WHKEY_TomcatRIO", FileName: fileName, NeedsThrottleType: true}
+	err := config.ResolveThrottleVariant(Virpil)
+	if err == nil || !strings.Contains(err.Error(), "THROTTLE_VPC.JPG") {
+		t.Errorf("ResolveThrottleVariant() error = %v, want missing variant error", err)
+	}
+
+	// The base image shows the Warthog, with or without a configured throttle
+	for _, throttle := range []ThrottleType{Warthog, NoThrottle} {
+		config = &Configuration{Name: "WHKEY_TomcatRIO", FileName: fileName, NeedsThrottleType: true}
+		if err := config.ResolveThrottleVariant(throttle); err != nil || config.FileName != fileName {
+			t.Errorf("ResolveThrottleVariant(%v) = %q, %v, want the base image", throttle, config.FileName, err)
+		}
+	}
+
+	config = &Configuration{Name: "LMFD", FileName: fileName}
+	if err := config.ResolveThrottleVariant(Virpil); err != nil || config.FileName != fileName {
+		t.Errorf("ResolveThrottleVariant() changed a configuration without needsThrottleType")
+	}
+}
+
+func TestReadModuleFiles_DefaultThrottle(t *testing.T) {
+	// The shipped module has no throttle variants, so it loads with the default settings
+	registry := loadTestModules(t)
+	match, err := registry.Resolve("F-14RHV/WHKEY_TomcatRIO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !match.Configuration.NeedsThrottleType {
+		t.Errorf("NeedsThrottleType = false, want it inherited from the WHKEY display")
+	}
+	if want := "F-14\\DCS F14RIO THROTTLE.JPG"; match.Configuration.FileName != want {
+		t.Errorf("FileName = %q, want %q", match.Configuration.FileName, want)
+	}
+}